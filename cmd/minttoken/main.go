@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/devduck123/servizio-be/internal/authtoken"
+	"github.com/devduck123/servizio-be/internal/config"
+)
+
+// minttoken prints a token that the server accepts when started with the
+// same -auth-secret, for trying the API without Firebase Auth.
+func main() {
+	secret := flag.String("secret", "", "secret the server was started with as -auth-secret")
+	projectID := flag.String("project-id", defaultProjectID(), "project the server was started with as -project-id, the audience of the token (env SERVIZIO_PROJECT_ID)")
+	uid := flag.String("uid", "local-user", "uid of the user to sign in as")
+	email := flag.String("email", "local-user@test.com", "email of the user")
+	provider := flag.String("provider", "password", "sign in provider, e.g. password, phone or anonymous")
+	expiresIn := flag.Duration("expires-in", time.Hour, "how long the token is valid for")
+	flag.Parse()
+
+	if *secret == "" {
+		log.Fatal("-secret is required")
+	}
+
+	token, err := authtoken.NewHMAC([]byte(*secret), *projectID).Mint(authtoken.MintInput{
+		UID:            *uid,
+		Email:          *email,
+		EmailVerified:  true,
+		SignInProvider: *provider,
+		ExpiresIn:      *expiresIn,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println(token)
+}
+
+// defaultProjectID is the project the server uses when given none.
+func defaultProjectID() string {
+	if projectID, ok := os.LookupEnv("SERVIZIO_PROJECT_ID"); ok {
+		return projectID
+	}
+	return config.Default().ProjectID
+}
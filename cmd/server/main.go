@@ -2,80 +2,68 @@ package main
 
 import (
 	"context"
-	"flag"
 	"fmt"
-	"log"
-	"net/http"
+	"net"
 	"os"
+	"os/signal"
+	"syscall"
 
-	"cloud.google.com/go/firestore"
-	firebase "firebase.google.com/go/v4"
-	"github.com/devduck123/servizio-be/internal/appointmentdao"
-	"github.com/devduck123/servizio-be/internal/businessdao"
-	"github.com/devduck123/servizio-be/internal/clientdao"
-	"github.com/devduck123/servizio-be/internal/images"
-	"github.com/devduck123/servizio-be/internal/server"
+	"github.com/devduck123/servizio-be/internal/bootstrap"
+	"github.com/devduck123/servizio-be/internal/config"
+	"github.com/devduck123/servizio-be/internal/logging"
 )
 
+// build is bootstrap.Build, tests replace it.
+var build = bootstrap.Build
+
 func main() {
-	ctx := context.Background()
-	if err := run(ctx); err != nil {
-		log.Fatal(err)
+	// Cloud Run and most process managers send SIGTERM before stopping us
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	if err := run(ctx, os.Args[1:]); err != nil {
+		stop()
+		logging.Default().Error("server stopped", "error", err)
+		os.Exit(1)
 	}
-	fmt.Println("done")
+	logging.Default().Info("server stopped")
 }
 
-func run(ctx context.Context) error {
-	local := flag.Bool("local", false, "local connects to a local running firestore database")
-	flag.Parse()
-	if local != nil && *local {
-		if err := os.Setenv("FIRESTORE_EMULATOR_HOST", "localhost:8080"); err != nil {
-			log.Fatal("failed to set FIRESTORE_EMULATOR_HOST environment variable", err)
-		}
-		log.Println("connecting to local running firestore database on localhost:8080")
-
-		if err := os.Setenv("FIREBASE_AUTH_EMULATOR_HOST", "localhost:9099"); err != nil {
-			log.Fatal("failed to set FIREBASE_AUTH_EMULATOR_HOST environment variable", err)
-		}
-		log.Println("connecting to firebase auth on localhost:9099")
-
-		if err := os.Setenv("STORAGE_EMULATOR_HOST", "localhost:9199"); err != nil {
-			log.Fatal("failed to set STORAGE_EMULATOR_HOST environment variable", err)
-		}
-		log.Println("connecting to firebase storage on localhost:9199")
-	}
-
-	projectID := "servizio-be"
-
-	fsClient, err := firestore.NewClient(ctx, projectID)
+func run(ctx context.Context, args []string) error {
+	cfg, err := config.Load(args, os.LookupEnv)
 	if err != nil {
 		return err
 	}
 
-	businessDao := businessdao.NewDao(fsClient, "businesses")
-	clientDao := clientdao.NewDao(fsClient, "clients")
-	appointmentDao := appointmentdao.NewDao(fsClient, "appointments")
-
-	im := &images.ImageManager{
-		BucketName: "servizio-be.appspot.com",
-	}
-	app, err := firebase.NewApp(ctx, &firebase.Config{
-		ProjectID: projectID,
-	})
+	// the clients Build makes keep its context to refresh tokens and export
+	// traces, so it must not end with ctx, before in-flight requests drain
+	app, err := build(context.Background(), cfg)
 	if err != nil {
 		return err
 	}
 
-	s := server.NewServer(businessDao, clientDao, appointmentDao, im, app)
-	http.HandleFunc("/businesses/", s.CORS(s.Logger(s.BusinessRouter)))
-	http.HandleFunc("/clients/", s.CORS(s.Logger(s.ClientRouter)))
-	http.HandleFunc("/appointments/", s.CORS(s.Logger(s.AppointmentRouter)))
-	fmt.Println("listening on port 3000")
-	if err := http.ListenAndServe(":3000", nil); err != nil {
+	if cfg.MetricsPort != 0 {
+		metricsAddr := fmt.Sprintf(":%d", cfg.MetricsPort)
+		metricsLn, err := net.Listen("tcp", metricsAddr)
+		if err != nil {
+			app.Close()
+			return err
+		}
+		logging.Default().Info("serving metrics", "addr", metricsAddr)
+		go func() {
+			if err := app.ServeMetrics(ctx, metricsLn, cfg.HTTP); err != nil {
+				logging.Default().Error("metrics server stopped", "error", err)
+			}
+		}()
+	}
+
+	addr := fmt.Sprintf(":%d", cfg.Port)
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		app.Close()
 		return err
 	}
+	logging.Default().Info("listening", "addr", addr)
 
-	return nil
+	// Serve drains in-flight requests and closes the Firestore client
+	return app.Serve(ctx, ln, cfg.HTTP)
 }
-
-// TODO: put port in variable
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/devduck123/servizio-be/internal/bootstrap"
+	"github.com/devduck123/servizio-be/internal/config"
+	"github.com/tj/assert"
+)
+
+func TestRun_StoresOutliveShutdown(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	port := ln.Addr().(*net.TCPAddr).Port
+	assert.NoError(t, ln.Close())
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	build = func(ctx context.Context, cfg config.Config) (*bootstrap.App, error) {
+		app, err := bootstrap.Build(ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+		handler := app.Handler
+		app.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			<-release
+			// stands for a Firestore client refreshing its token with the
+			// context it was made with
+			if err := ctx.Err(); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			handler.ServeHTTP(w, r)
+		})
+		return app, nil
+	}
+	t.Cleanup(func() {
+		build = bootstrap.Build
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- run(ctx, []string{"-memory", "-port", strconv.Itoa(port), "-metrics-port", "0", "-log-level", "error"})
+	}()
+
+	status := make(chan int, 1)
+	go func() {
+		url := "http://127.0.0.1:" + strconv.Itoa(port) + "/businesses/"
+		for {
+			resp, err := http.Get(url)
+			if err != nil {
+				// not listening yet
+				time.Sleep(10 * time.Millisecond)
+				continue
+			}
+			resp.Body.Close()
+			status <- resp.StatusCode
+			return
+		}
+	}()
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("request did not start")
+	}
+	// the store call runs once shutdown has started
+	cancel()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+
+	select {
+	case got := <-status:
+		assert.Equal(t, http.StatusOK, got)
+	case <-time.After(5 * time.Second):
+		t.Fatal("request did not finish")
+	}
+	assert.NoError(t, <-runErr)
+}
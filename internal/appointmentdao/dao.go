@@ -6,17 +6,116 @@ import (
 	"time"
 
 	"cloud.google.com/go/firestore"
+	"github.com/devduck123/servizio-be/internal/pagination"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
-var ErrAppointmentNotFound = errors.New("appointment not found")
+var (
+	ErrAppointmentNotFound = errors.New("appointment not found")
+	ErrAppointmentConflict = errors.New("appointment conflicts with an existing appointment")
+	ErrInvalidTransition   = errors.New("appointment status cannot be changed that way")
+	ErrAppointmentClosed   = errors.New("appointment can no longer be changed")
+)
+
+// MaxDuration is the longest an appointment can last. Knowing it lets the
+// queries looking for overlaps skip appointments that start before the
+// interval by more than it, instead of reading the whole history.
+const MaxDuration = 24 * time.Hour
 
 type Appointment struct {
-	ID         string    `json:"id" firestore:"-"`
-	ClientID   string    `json:"clientId" firestore:"clientId"`
-	BusinessID string    `json:"businessId" firestore:"businessId"`
-	Date       time.Time `json:"date" firestore:"date"`
+	ID         string `json:"id" firestore:"-"`
+	ClientID   string `json:"clientId" firestore:"clientId"`
+	BusinessID string `json:"businessId" firestore:"businessId"`
+	// Date is when the appointment starts
+	Date          time.Time      `json:"date" firestore:"date"`
+	EndDate       time.Time      `json:"endDate" firestore:"endDate"`
+	Status        Status         `json:"status" firestore:"status"`
+	StatusHistory []StatusChange `json:"statusHistory,omitempty" firestore:"statusHistory,omitempty"`
+	Reschedules   []Reschedule   `json:"reschedules,omitempty" firestore:"reschedules,omitempty"`
+}
+
+// Reschedule records the interval an appointment was moved away from.
+type Reschedule struct {
+	PreviousDate    time.Time `json:"previousDate" firestore:"previousDate"`
+	PreviousEndDate time.Time `json:"previousEndDate" firestore:"previousEndDate"`
+	RescheduledBy   string    `json:"rescheduledBy" firestore:"rescheduledBy"`
+	RescheduledAt   time.Time `json:"rescheduledAt" firestore:"rescheduledAt"`
+}
+
+func appointmentFromSnapshot(snapshot *firestore.DocumentSnapshot) (Appointment, error) {
+	var appointment Appointment
+	if err := snapshot.DataTo(&appointment); err != nil {
+		return Appointment{}, err
+	}
+	appointment.ID = snapshot.Ref.ID
+	// appointments booked before statuses existed are still pending
+	if appointment.Status == "" {
+		appointment.Status = StatusPending
+	}
+
+	return appointment, nil
+}
+
+// Overlaps reports whether the appointment intersects [start, end).
+func (a Appointment) Overlaps(start, end time.Time) bool {
+	// appointments booked before end dates existed only occupy their start
+	if a.EndDate.IsZero() {
+		return !a.Date.Before(start) && a.Date.Before(end)
+	}
+
+	return a.Date.Before(end) && a.EndDate.After(start)
+}
+
+// blocks reports whether a keeps appointment from being booked, which is
+// when they share the business or the client and their intervals overlap.
+func (a Appointment) blocks(appointment Appointment) bool {
+	if a.ID == appointment.ID || !a.Status.OccupiesSlot() {
+		return false
+	}
+	if a.BusinessID != appointment.BusinessID && a.ClientID != appointment.ClientID {
+		return false
+	}
+
+	return a.Overlaps(appointment.Date, appointment.EndDate)
+}
+
+// changeStatus applies input to the appointment and returns the change to
+// record, or ErrInvalidTransition if the actor cannot make it.
+func (a *Appointment) changeStatus(input UpdateStatusInput) (StatusChange, error) {
+	if !CanTransition(a.Status, input.Status, input.Actor) {
+		return StatusChange{}, ErrInvalidTransition
+	}
+
+	change := StatusChange{
+		Status:    input.Status,
+		ChangedBy: input.UserID,
+		ChangedAt: time.Now().UTC(),
+	}
+	a.Status = change.Status
+	a.StatusHistory = append(a.StatusHistory, change)
+
+	return change, nil
+}
+
+// reschedule moves the appointment to the interval in input and returns the
+// change to record, or ErrAppointmentClosed if it can no longer be moved.
+func (a *Appointment) reschedule(input RescheduleInput) (Reschedule, error) {
+	if !a.Status.IsOpen() {
+		return Reschedule{}, ErrAppointmentClosed
+	}
+
+	reschedule := Reschedule{
+		PreviousDate:    a.Date,
+		PreviousEndDate: a.EndDate,
+		RescheduledBy:   input.UserID,
+		RescheduledAt:   time.Now().UTC(),
+	}
+	a.Date = input.Date
+	a.EndDate = input.EndDate
+	a.Reschedules = append(a.Reschedules, reschedule)
+
+	return reschedule, nil
 }
 
 type Dao struct {
@@ -40,12 +139,11 @@ func (dao *Dao) GetAppointment(ctx context.Context, id string) (*Appointment, er
 		}
 		return nil, err
 	}
-	var appointment Appointment
-	if err := snapshot.DataTo(&appointment); err != nil {
+	appointment, err := appointmentFromSnapshot(snapshot)
+	if err != nil {
 		return nil, err
 	}
 
-	appointment.ID = docRef.ID
 	return &appointment, nil
 }
 
@@ -53,16 +151,68 @@ type GetAllAppointmentsInput struct {
 	// OPTIONAL to filter by businesses
 	BusinessID string
 	ClientID   string
+	Status     Status
+	// From and To are optional, they filter by start date in [From, To)
+	From time.Time
+	To   time.Time
+	// Descending lists the latest appointments first
+	Descending bool
+	Page       pagination.Input
 }
 
-func (dao *Dao) GetAllAppointments(ctx context.Context, input GetAllAppointmentsInput) ([]Appointment, error) {
-	query := dao.fsClient.Collection(dao.appointmentCollectionName).Query
+// GetAllAppointments returns a page of appointments ordered by start date
+// and the cursor to start the next page after, empty on the last page.
+// The combinations of filters are backed by the indexes in
+// firestore.indexes.json.
+func (dao *Dao) GetAllAppointments(ctx context.Context, input GetAllAppointmentsInput) ([]Appointment, string, error) {
+	direction := firestore.Asc
+	if input.Descending {
+		direction = firestore.Desc
+	}
+
+	collection := dao.fsClient.Collection(dao.appointmentCollectionName)
+	query := collection.OrderBy("date", direction).OrderBy(firestore.DocumentID, direction)
 	if input.BusinessID != "" {
 		query = query.Where("businessId", "==", input.BusinessID)
 	}
 	if input.ClientID != "" {
 		query = query.Where("clientId", "==", input.ClientID)
 	}
+	if input.Status != "" {
+		query = query.Where("status", "==", input.Status)
+	}
+	if !input.From.IsZero() {
+		query = query.Where("date", ">=", input.From)
+	}
+	if !input.To.IsZero() {
+		query = query.Where("date", "<", input.To)
+	}
+	snapshots, next, err := pagination.GetPage(ctx, query, input.Page, "date")
+	if err != nil {
+		return nil, "", err
+	}
+
+	appointments := make([]Appointment, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		appointment, err := appointmentFromSnapshot(snapshot)
+		if err != nil {
+			return nil, "", err
+		}
+		appointments = append(appointments, appointment)
+	}
+
+	return appointments, next, nil
+}
+
+// GetBusinessAppointmentsBetween returns the appointments of a business
+// that overlap [from, to).
+func (dao *Dao) GetBusinessAppointmentsBetween(ctx context.Context, businessID string, from, to time.Time) ([]Appointment, error) {
+	// appointments starting more than MaxDuration before from are over by
+	// then
+	query := dao.fsClient.Collection(dao.appointmentCollectionName).
+		Where("businessId", "==", businessID).
+		Where("date", ">", from.Add(-MaxDuration)).
+		Where("date", "<", to)
 	snapshots, err := query.Documents(ctx).GetAll()
 	if err != nil {
 		return nil, err
@@ -70,11 +220,13 @@ func (dao *Dao) GetAllAppointments(ctx context.Context, input GetAllAppointments
 
 	appointments := make([]Appointment, 0, len(snapshots))
 	for _, snapshot := range snapshots {
-		var appointment Appointment
-		if err := snapshot.DataTo(&appointment); err != nil {
+		appointment, err := appointmentFromSnapshot(snapshot)
+		if err != nil {
 			return nil, err
 		}
-		appointment.ID = snapshot.Ref.ID
+		if !appointment.Overlaps(from, to) {
+			continue
+		}
 		appointments = append(appointments, appointment)
 	}
 
@@ -85,20 +237,158 @@ type CreateInput struct {
 	ClientID   string
 	BusinessID string
 	Date       time.Time
+	EndDate    time.Time
 }
 
+// Create stores a new appointment, returning ErrAppointmentConflict if the
+// business or the client already has an appointment overlapping it.
+// The check and the write run in a single transaction so concurrent
+// bookings cannot both succeed.
 func (dao *Dao) Create(ctx context.Context, input CreateInput) (*Appointment, error) {
 	appointment := Appointment{
 		ClientID:   input.ClientID,
 		BusinessID: input.BusinessID,
 		Date:       input.Date,
+		EndDate:    input.EndDate,
+		Status:     StatusPending,
+	}
+
+	docRef := dao.fsClient.Collection(dao.appointmentCollectionName).NewDoc()
+	err := dao.fsClient.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		if err := dao.checkConflicts(tx, appointment); err != nil {
+			return err
+		}
+
+		return tx.Create(docRef, appointment)
+	})
+	if err != nil {
+		return nil, err
+	}
+	appointment.ID = docRef.ID
+	return &appointment, nil
+}
+
+// checkConflicts returns ErrAppointmentConflict if another appointment of
+// the same business or client overlaps the given one.
+func (dao *Dao) checkConflicts(tx *firestore.Transaction, appointment Appointment) error {
+	collection := dao.fsClient.Collection(dao.appointmentCollectionName)
+	// firestore only allows a range filter on a single field, so the
+	// query narrows by start date, bounded below by the longest an
+	// appointment can last, and the end date is compared below
+	earliest := appointment.Date.Add(-MaxDuration)
+	queries := []firestore.Query{
+		collection.Where("businessId", "==", appointment.BusinessID).Where("date", ">", earliest).Where("date", "<", appointment.EndDate),
+		collection.Where("clientId", "==", appointment.ClientID).Where("date", ">", earliest).Where("date", "<", appointment.EndDate),
+	}
+	for _, query := range queries {
+		snapshots, err := tx.Documents(query).GetAll()
+		if err != nil {
+			return err
+		}
+
+		for _, snapshot := range snapshots {
+			existing, err := appointmentFromSnapshot(snapshot)
+			if err != nil {
+				return err
+			}
+			if existing.blocks(appointment) {
+				return ErrAppointmentConflict
+			}
+		}
+	}
+
+	return nil
+}
+
+type UpdateStatusInput struct {
+	Status Status
+	Actor  Actor
+	// UserID is recorded in the status history as who made the change
+	UserID string
+}
+
+// UpdateStatus moves an appointment to a new status, returning
+// ErrInvalidTransition if the actor is not allowed to make that change.
+func (dao *Dao) UpdateStatus(ctx context.Context, id string, input UpdateStatusInput) (*Appointment, error) {
+	docRef := dao.fsClient.Collection(dao.appointmentCollectionName).Doc(id)
+
+	var appointment Appointment
+	err := dao.fsClient.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		snapshot, err := tx.Get(docRef)
+		if err != nil {
+			if status.Code(err) == codes.NotFound {
+				return ErrAppointmentNotFound
+			}
+			return err
+		}
+		appointment, err = appointmentFromSnapshot(snapshot)
+		if err != nil {
+			return err
+		}
+
+		change, err := appointment.changeStatus(input)
+		if err != nil {
+			return err
+		}
+
+		return tx.Update(docRef, []firestore.Update{
+			{Path: "status", Value: change.Status},
+			{Path: "statusHistory", Value: firestore.ArrayUnion(change)},
+		})
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	doc, _, err := dao.fsClient.Collection(dao.appointmentCollectionName).Add(ctx, appointment)
+	return &appointment, nil
+}
+
+type RescheduleInput struct {
+	Date    time.Time
+	EndDate time.Time
+	// UserID is recorded in the reschedule history as who made the change
+	UserID string
+}
+
+// Reschedule moves an open appointment to a new interval, keeping its ID.
+// Like Create, it returns ErrAppointmentConflict if the new interval
+// overlaps another appointment of the same business or client.
+func (dao *Dao) Reschedule(ctx context.Context, id string, input RescheduleInput) (*Appointment, error) {
+	docRef := dao.fsClient.Collection(dao.appointmentCollectionName).Doc(id)
+
+	var appointment Appointment
+	err := dao.fsClient.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		snapshot, err := tx.Get(docRef)
+		if err != nil {
+			if status.Code(err) == codes.NotFound {
+				return ErrAppointmentNotFound
+			}
+			return err
+		}
+		appointment, err = appointmentFromSnapshot(snapshot)
+		if err != nil {
+			return err
+		}
+
+		reschedule, err := appointment.reschedule(input)
+		if err != nil {
+			return err
+		}
+
+		if err := dao.checkConflicts(tx, appointment); err != nil {
+			return err
+		}
+
+		return tx.Update(docRef, []firestore.Update{
+			{Path: "date", Value: appointment.Date},
+			{Path: "endDate", Value: appointment.EndDate},
+			{Path: "reschedules", Value: firestore.ArrayUnion(reschedule)},
+		})
+	})
 	if err != nil {
 		return nil, err
 	}
-	appointment.ID = doc.ID
+
 	return &appointment, nil
 }
 
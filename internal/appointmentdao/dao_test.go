@@ -65,18 +65,22 @@ func TestGetAppointment_NotExists(t *testing.T) {
 func TestGetAllAppointments_ByClientID_ByBusinessID(t *testing.T) {
 	ctx := context.Background()
 	dao := createTestDao(ctx, t)
+	baseTime := time.Date(2023, 4, 20, 9, 0, 0, 0, time.UTC)
 
 	createInput := CreateInput{
 		ClientID:   "client1",
 		BusinessID: "google",
+		Date:       baseTime.Add(1 * time.Hour),
 	}
 	createInput2 := CreateInput{
 		ClientID:   "client1",
 		BusinessID: "apple",
+		Date:       baseTime.Add(2 * time.Hour),
 	}
 	createInput3 := CreateInput{
 		ClientID:   "client1",
 		BusinessID: "apple",
+		Date:       baseTime.Add(3 * time.Hour),
 	}
 	_, err := dao.Create(ctx, createInput)
 	assert.NoError(t, err)
@@ -89,7 +93,7 @@ func TestGetAllAppointments_ByClientID_ByBusinessID(t *testing.T) {
 		ClientID:   "client1",
 		BusinessID: "apple",
 	}
-	allAppointments, err := dao.GetAllAppointments(ctx, getAllAppointmentsInput)
+	allAppointments, _, err := dao.GetAllAppointments(ctx, getAllAppointmentsInput)
 	assert.NoError(t, err)
 	assert.Len(t, allAppointments, 2)
 }
@@ -97,18 +101,22 @@ func TestGetAllAppointments_ByClientID_ByBusinessID(t *testing.T) {
 func TestGetAllAppointments_ByClientID(t *testing.T) {
 	ctx := context.Background()
 	dao := createTestDao(ctx, t)
+	baseTime := time.Date(2023, 4, 20, 9, 0, 0, 0, time.UTC)
 
 	createInput := CreateInput{
 		ClientID:   "client1",
 		BusinessID: "google",
+		Date:       baseTime.Add(1 * time.Hour),
 	}
 	createInput2 := CreateInput{
 		ClientID:   "client1",
 		BusinessID: "apple",
+		Date:       baseTime.Add(2 * time.Hour),
 	}
 	createInput3 := CreateInput{
 		ClientID:   "client2",
 		BusinessID: "apple",
+		Date:       baseTime.Add(3 * time.Hour),
 	}
 	_, err := dao.Create(ctx, createInput)
 	assert.NoError(t, err)
@@ -120,7 +128,7 @@ func TestGetAllAppointments_ByClientID(t *testing.T) {
 	getAllAppointmentsInput := GetAllAppointmentsInput{
 		ClientID: "client1",
 	}
-	allAppointments, err := dao.GetAllAppointments(ctx, getAllAppointmentsInput)
+	allAppointments, _, err := dao.GetAllAppointments(ctx, getAllAppointmentsInput)
 	assert.NoError(t, err)
 	assert.Len(t, allAppointments, 2)
 }
@@ -129,18 +137,22 @@ func TestGetAllAppointments_ByClientID(t *testing.T) {
 func TestGetAllAppointments(t *testing.T) {
 	ctx := context.Background()
 	dao := createTestDao(ctx, t)
+	baseTime := time.Date(2023, 4, 20, 9, 0, 0, 0, time.UTC)
 
 	createInput := CreateInput{
 		ClientID:   "client1",
 		BusinessID: "google",
+		Date:       baseTime.Add(1 * time.Hour),
 	}
 	createInput2 := CreateInput{
 		ClientID:   "client2",
 		BusinessID: "google",
+		Date:       baseTime.Add(2 * time.Hour),
 	}
 	createInput3 := CreateInput{
 		ClientID:   "client3",
 		BusinessID: "apple",
+		Date:       baseTime.Add(3 * time.Hour),
 	}
 	_, err := dao.Create(ctx, createInput)
 	assert.NoError(t, err)
@@ -150,7 +162,7 @@ func TestGetAllAppointments(t *testing.T) {
 	assert.NoError(t, err)
 
 	getAllAppointmentsInput := GetAllAppointmentsInput{}
-	allAppointments, err := dao.GetAllAppointments(ctx, getAllAppointmentsInput)
+	allAppointments, _, err := dao.GetAllAppointments(ctx, getAllAppointmentsInput)
 	assert.NoError(t, err)
 	assert.Len(t, allAppointments, 3)
 }
@@ -165,6 +177,7 @@ func TestCreateAppointment(t *testing.T) {
 		ClientID:   "foo",
 		BusinessID: "bar",
 		Date:       inputTime,
+		EndDate:    inputTime.Add(time.Hour),
 	}
 	appointment, err := dao.Create(ctx, input)
 	assert.NoError(t, err)
@@ -173,6 +186,265 @@ func TestCreateAppointment(t *testing.T) {
 	assert.Equal(t, input.ClientID, appointment.ClientID)
 	assert.Equal(t, input.BusinessID, appointment.BusinessID)
 	assert.Equal(t, input.Date, appointment.Date)
+	assert.Equal(t, input.EndDate, appointment.EndDate)
+}
+
+func TestCreateAppointment_Conflict(t *testing.T) {
+	ctx := context.Background()
+	dao := createTestDao(ctx, t)
+
+	inputTime, err := time.Parse("2006-01-02 15:04", "2023-04-20 04:35")
+	assert.NoError(t, err)
+	_, err = dao.Create(ctx, CreateInput{
+		ClientID:   "foo",
+		BusinessID: "bar",
+		Date:       inputTime,
+		EndDate:    inputTime.Add(time.Hour),
+	})
+	assert.NoError(t, err)
+
+	// same business, different client, overlapping the end
+	appointment, err := dao.Create(ctx, CreateInput{
+		ClientID:   "baz",
+		BusinessID: "bar",
+		Date:       inputTime.Add(30 * time.Minute),
+		EndDate:    inputTime.Add(90 * time.Minute),
+	})
+	assert.Equal(t, ErrAppointmentConflict, err)
+	assert.Nil(t, appointment)
+
+	// same client, different business, overlapping the start
+	appointment, err = dao.Create(ctx, CreateInput{
+		ClientID:   "foo",
+		BusinessID: "qux",
+		Date:       inputTime.Add(-30 * time.Minute),
+		EndDate:    inputTime.Add(30 * time.Minute),
+	})
+	assert.Equal(t, ErrAppointmentConflict, err)
+	assert.Nil(t, appointment)
+
+	// same parties, starting right when the first one ends
+	appointment, err = dao.Create(ctx, CreateInput{
+		ClientID:   "foo",
+		BusinessID: "bar",
+		Date:       inputTime.Add(time.Hour),
+		EndDate:    inputTime.Add(2 * time.Hour),
+	})
+	assert.NoError(t, err)
+	assert.NotNil(t, appointment)
+}
+
+func TestCreateAppointment_ConflictWithLongest(t *testing.T) {
+	ctx := context.Background()
+	dao := createTestDao(ctx, t)
+
+	inputTime, err := time.Parse("2006-01-02 15:04", "2023-04-20 04:35")
+	assert.NoError(t, err)
+	_, err = dao.Create(ctx, CreateInput{
+		ClientID:   "foo",
+		BusinessID: "bar",
+		Date:       inputTime,
+		EndDate:    inputTime.Add(MaxDuration),
+	})
+	assert.NoError(t, err)
+
+	// the lower bound of the query still finds it in its last minute
+	_, err = dao.Create(ctx, CreateInput{
+		ClientID:   "baz",
+		BusinessID: "bar",
+		Date:       inputTime.Add(MaxDuration - time.Minute),
+		EndDate:    inputTime.Add(MaxDuration + time.Hour),
+	})
+	assert.Equal(t, ErrAppointmentConflict, err)
+}
+
+func TestCreateAppointment_CancelledDoesNotConflict(t *testing.T) {
+	ctx := context.Background()
+	dao := createTestDao(ctx, t)
+
+	inputTime, err := time.Parse("2006-01-02 15:04", "2023-04-20 04:35")
+	assert.NoError(t, err)
+	input := CreateInput{
+		ClientID:   "foo",
+		BusinessID: "bar",
+		Date:       inputTime,
+		EndDate:    inputTime.Add(time.Hour),
+	}
+	appointment, err := dao.Create(ctx, input)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusPending, appointment.Status)
+
+	_, err = dao.UpdateStatus(ctx, appointment.ID, UpdateStatusInput{
+		Status: StatusCancelled,
+		Actor:  ActorClient,
+	})
+	assert.NoError(t, err)
+
+	_, err = dao.Create(ctx, input)
+	assert.NoError(t, err)
+}
+
+func TestAppointmentOverlaps(t *testing.T) {
+	start := time.Date(2023, 4, 20, 9, 0, 0, 0, time.UTC)
+	appointment := Appointment{
+		Date:    start,
+		EndDate: start.Add(time.Hour),
+	}
+
+	assert.True(t, appointment.Overlaps(start, start.Add(time.Hour)))
+	assert.True(t, appointment.Overlaps(start.Add(-time.Minute), start.Add(time.Minute)))
+	assert.True(t, appointment.Overlaps(start.Add(59*time.Minute), start.Add(2*time.Hour)))
+	assert.False(t, appointment.Overlaps(start.Add(time.Hour), start.Add(2*time.Hour)))
+	assert.False(t, appointment.Overlaps(start.Add(-time.Hour), start))
+
+	// appointments without an end date only occupy their start
+	legacy := Appointment{
+		Date: start,
+	}
+	assert.True(t, legacy.Overlaps(start, start.Add(time.Minute)))
+	assert.False(t, legacy.Overlaps(start.Add(time.Minute), start.Add(time.Hour)))
+}
+
+func TestUpdateStatus(t *testing.T) {
+	ctx := context.Background()
+	dao := createTestDao(ctx, t)
+
+	appointment, err := dao.Create(ctx, CreateInput{
+		ClientID:   "foo",
+		BusinessID: "bar",
+	})
+	assert.NoError(t, err)
+
+	updated, err := dao.UpdateStatus(ctx, appointment.ID, UpdateStatusInput{
+		Status: StatusConfirmed,
+		Actor:  ActorBusiness,
+		UserID: "owner",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, StatusConfirmed, updated.Status)
+
+	gotAppointment, err := dao.GetAppointment(ctx, appointment.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusConfirmed, gotAppointment.Status)
+	assert.Len(t, gotAppointment.StatusHistory, 1)
+	assert.Equal(t, "owner", gotAppointment.StatusHistory[0].ChangedBy)
+	assert.False(t, gotAppointment.StatusHistory[0].ChangedAt.IsZero())
+}
+
+func TestUpdateStatus_InvalidTransition(t *testing.T) {
+	ctx := context.Background()
+	dao := createTestDao(ctx, t)
+
+	appointment, err := dao.Create(ctx, CreateInput{
+		ClientID:   "foo",
+		BusinessID: "bar",
+	})
+	assert.NoError(t, err)
+
+	updated, err := dao.UpdateStatus(ctx, appointment.ID, UpdateStatusInput{
+		Status: StatusCompleted,
+		Actor:  ActorClient,
+	})
+	assert.Equal(t, ErrInvalidTransition, err)
+	assert.Nil(t, updated)
+}
+
+func TestUpdateStatus_NotExists(t *testing.T) {
+	ctx := context.Background()
+	dao := createTestDao(ctx, t)
+
+	updated, err := dao.UpdateStatus(ctx, "notexists", UpdateStatusInput{
+		Status: StatusCancelled,
+		Actor:  ActorClient,
+	})
+	assert.Equal(t, ErrAppointmentNotFound, err)
+	assert.Nil(t, updated)
+}
+
+func TestReschedule(t *testing.T) {
+	ctx := context.Background()
+	dao := createTestDao(ctx, t)
+
+	inputTime, err := time.Parse("2006-01-02 15:04", "2023-04-20 04:35")
+	assert.NoError(t, err)
+	appointment, err := dao.Create(ctx, CreateInput{
+		ClientID:   "foo",
+		BusinessID: "bar",
+		Date:       inputTime,
+		EndDate:    inputTime.Add(time.Hour),
+	})
+	assert.NoError(t, err)
+
+	// overlapping its own previous interval is fine
+	rescheduled, err := dao.Reschedule(ctx, appointment.ID, RescheduleInput{
+		Date:    inputTime.Add(30 * time.Minute),
+		EndDate: inputTime.Add(90 * time.Minute),
+		UserID:  "client",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, appointment.ID, rescheduled.ID)
+
+	gotAppointment, err := dao.GetAppointment(ctx, appointment.ID)
+	assert.NoError(t, err)
+	assert.True(t, inputTime.Add(30*time.Minute).Equal(gotAppointment.Date))
+	assert.True(t, inputTime.Add(90*time.Minute).Equal(gotAppointment.EndDate))
+	assert.Len(t, gotAppointment.Reschedules, 1)
+	assert.True(t, inputTime.Equal(gotAppointment.Reschedules[0].PreviousDate))
+	assert.Equal(t, "client", gotAppointment.Reschedules[0].RescheduledBy)
+}
+
+func TestReschedule_Conflict(t *testing.T) {
+	ctx := context.Background()
+	dao := createTestDao(ctx, t)
+
+	inputTime, err := time.Parse("2006-01-02 15:04", "2023-04-20 04:35")
+	assert.NoError(t, err)
+	_, err = dao.Create(ctx, CreateInput{
+		ClientID:   "foo",
+		BusinessID: "bar",
+		Date:       inputTime,
+		EndDate:    inputTime.Add(time.Hour),
+	})
+	assert.NoError(t, err)
+	appointment, err := dao.Create(ctx, CreateInput{
+		ClientID:   "baz",
+		BusinessID: "bar",
+		Date:       inputTime.Add(2 * time.Hour),
+		EndDate:    inputTime.Add(3 * time.Hour),
+	})
+	assert.NoError(t, err)
+
+	rescheduled, err := dao.Reschedule(ctx, appointment.ID, RescheduleInput{
+		Date:    inputTime.Add(30 * time.Minute),
+		EndDate: inputTime.Add(90 * time.Minute),
+	})
+	assert.Equal(t, ErrAppointmentConflict, err)
+	assert.Nil(t, rescheduled)
+}
+
+func TestReschedule_Closed(t *testing.T) {
+	ctx := context.Background()
+	dao := createTestDao(ctx, t)
+
+	appointment, err := dao.Create(ctx, CreateInput{
+		ClientID:   "foo",
+		BusinessID: "bar",
+	})
+	assert.NoError(t, err)
+	_, err = dao.UpdateStatus(ctx, appointment.ID, UpdateStatusInput{
+		Status: StatusCancelled,
+		Actor:  ActorClient,
+	})
+	assert.NoError(t, err)
+
+	inputTime, err := time.Parse("2006-01-02 15:04", "2023-04-20 04:35")
+	assert.NoError(t, err)
+	rescheduled, err := dao.Reschedule(ctx, appointment.ID, RescheduleInput{
+		Date:    inputTime,
+		EndDate: inputTime.Add(time.Hour),
+	})
+	assert.Equal(t, ErrAppointmentClosed, err)
+	assert.Nil(t, rescheduled)
 }
 
 func TestDeleteAppointment(t *testing.T) {
@@ -193,3 +465,73 @@ func TestDeleteAppointment(t *testing.T) {
 	err = dao.Delete(ctx, appointment.ID)
 	assert.NoError(t, err)
 }
+
+func TestGetAllAppointments_Filters(t *testing.T) {
+	ctx := context.Background()
+	dao := createTestDao(ctx, t)
+	baseTime := time.Date(2032, 12, 2, 9, 0, 0, 0, time.UTC)
+
+	var appointments []*Appointment
+	for i := 0; i < 4; i++ {
+		appointment, err := dao.Create(ctx, CreateInput{
+			ClientID:   fmt.Sprintf("client%v", i),
+			BusinessID: "google",
+			Date:       baseTime.Add(time.Duration(i) * time.Hour),
+			EndDate:    baseTime.Add(time.Duration(i)*time.Hour + 30*time.Minute),
+		})
+		assert.NoError(t, err)
+		appointments = append(appointments, appointment)
+	}
+	_, err := dao.UpdateStatus(ctx, appointments[2].ID, UpdateStatusInput{
+		Status: StatusConfirmed,
+		Actor:  ActorBusiness,
+	})
+	assert.NoError(t, err)
+
+	inRange, _, err := dao.GetAllAppointments(ctx, GetAllAppointmentsInput{
+		BusinessID: "google",
+		From:       baseTime.Add(1 * time.Hour),
+		To:         baseTime.Add(3 * time.Hour),
+		Descending: true,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, inRange, 2)
+	assert.Equal(t, appointments[2].ID, inRange[0].ID)
+	assert.Equal(t, appointments[1].ID, inRange[1].ID)
+
+	confirmed, _, err := dao.GetAllAppointments(ctx, GetAllAppointmentsInput{
+		BusinessID: "google",
+		Status:     StatusConfirmed,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, confirmed, 1)
+	assert.Equal(t, appointments[2].ID, confirmed[0].ID)
+}
+
+func TestGetBusinessAppointmentsBetween(t *testing.T) {
+	ctx := context.Background()
+	dao := createTestDao(ctx, t)
+
+	businessID := uuid.New().String()
+	from := time.Date(2032, 12, 2, 9, 0, 0, 0, time.UTC)
+	to := from.Add(8 * time.Hour)
+	inputs := []CreateInput{
+		// long over by from
+		{ClientID: "foo", BusinessID: businessID, Date: from.Add(-48 * time.Hour), EndDate: from.Add(-47 * time.Hour)},
+		// started before from and still going on
+		{ClientID: "bar", BusinessID: businessID, Date: from.Add(-time.Hour), EndDate: from.Add(30 * time.Minute)},
+		{ClientID: "baz", BusinessID: businessID, Date: from.Add(time.Hour), EndDate: from.Add(2 * time.Hour)},
+		{ClientID: "qux", BusinessID: businessID, Date: to, EndDate: to.Add(time.Hour)},
+	}
+	for _, input := range inputs {
+		_, err := dao.Create(ctx, input)
+		assert.NoError(t, err)
+	}
+
+	appointments, err := dao.GetBusinessAppointmentsBetween(ctx, businessID, from, to)
+	assert.NoError(t, err)
+	assert.Len(t, appointments, 2)
+	for _, appointment := range appointments {
+		assert.True(t, appointment.Overlaps(from, to))
+	}
+}
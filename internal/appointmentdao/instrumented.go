@@ -0,0 +1,82 @@
+package appointmentdao
+
+import (
+	"context"
+	"time"
+
+	"github.com/devduck123/servizio-be/internal/observe"
+	"github.com/devduck123/servizio-be/internal/pagination"
+)
+
+const storeName = "appointmentdao"
+
+// expectedErrors are answers of the store rather than failures of it.
+var expectedErrors = []error{
+	ErrAppointmentNotFound,
+	ErrAppointmentConflict,
+	ErrInvalidTransition,
+	ErrAppointmentClosed,
+	pagination.ErrInvalidCursor,
+}
+
+// instrumented reports every call of the wrapped store to observe.
+type instrumented struct {
+	store   Store
+	observe observe.Func
+}
+
+// Instrument wraps store so that fn sees every call, bootstrap uses it to
+// count the calls made to Firestore. fn sees the errors in expectedErrors
+// as nil.
+func Instrument(store Store, fn observe.Func) Store {
+	return instrumented{store: store, observe: fn}
+}
+
+func (i instrumented) GetAppointment(ctx context.Context, id string) (*Appointment, error) {
+	ctx, done := i.observe(ctx, storeName, "GetAppointment")
+	appointment, err := i.store.GetAppointment(ctx, id)
+	done(observe.Failure(err, expectedErrors...))
+	return appointment, err
+}
+
+func (i instrumented) GetAllAppointments(ctx context.Context, input GetAllAppointmentsInput) ([]Appointment, string, error) {
+	ctx, done := i.observe(ctx, storeName, "GetAllAppointments")
+	appointments, next, err := i.store.GetAllAppointments(ctx, input)
+	done(observe.Failure(err, expectedErrors...))
+	return appointments, next, err
+}
+
+func (i instrumented) GetBusinessAppointmentsBetween(ctx context.Context, businessID string, from, to time.Time) ([]Appointment, error) {
+	ctx, done := i.observe(ctx, storeName, "GetBusinessAppointmentsBetween")
+	appointments, err := i.store.GetBusinessAppointmentsBetween(ctx, businessID, from, to)
+	done(observe.Failure(err, expectedErrors...))
+	return appointments, err
+}
+
+func (i instrumented) Create(ctx context.Context, input CreateInput) (*Appointment, error) {
+	ctx, done := i.observe(ctx, storeName, "Create")
+	appointment, err := i.store.Create(ctx, input)
+	done(observe.Failure(err, expectedErrors...))
+	return appointment, err
+}
+
+func (i instrumented) UpdateStatus(ctx context.Context, id string, input UpdateStatusInput) (*Appointment, error) {
+	ctx, done := i.observe(ctx, storeName, "UpdateStatus")
+	appointment, err := i.store.UpdateStatus(ctx, id, input)
+	done(observe.Failure(err, expectedErrors...))
+	return appointment, err
+}
+
+func (i instrumented) Reschedule(ctx context.Context, id string, input RescheduleInput) (*Appointment, error) {
+	ctx, done := i.observe(ctx, storeName, "Reschedule")
+	appointment, err := i.store.Reschedule(ctx, id, input)
+	done(observe.Failure(err, expectedErrors...))
+	return appointment, err
+}
+
+func (i instrumented) Delete(ctx context.Context, id string) error {
+	ctx, done := i.observe(ctx, storeName, "Delete")
+	err := i.store.Delete(ctx, id)
+	done(observe.Failure(err, expectedErrors...))
+	return err
+}
@@ -0,0 +1,196 @@
+package appointmentdao
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/devduck123/servizio-be/internal/pagination"
+	"github.com/google/uuid"
+)
+
+// MemoryStore keeps appointments in memory, for tests and for running the
+// server without Firestore. It enforces the same conflict and status rules
+// as Dao.
+type MemoryStore struct {
+	mu           sync.Mutex
+	appointments map[string]Appointment
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		appointments: make(map[string]Appointment),
+	}
+}
+
+// copyAppointment keeps callers from modifying the stored history.
+func copyAppointment(appointment Appointment) *Appointment {
+	appointment.StatusHistory = append([]StatusChange(nil), appointment.StatusHistory...)
+	appointment.Reschedules = append([]Reschedule(nil), appointment.Reschedules...)
+	return &appointment
+}
+
+// before orders appointments like Dao.GetAllAppointments, by start date
+// and then by ID.
+func before(a, b Appointment, descending bool) bool {
+	if descending {
+		a, b = b, a
+	}
+	if !a.Date.Equal(b.Date) {
+		return a.Date.Before(b.Date)
+	}
+	return a.ID < b.ID
+}
+
+func (m *MemoryStore) checkConflicts(appointment Appointment) error {
+	for _, existing := range m.appointments {
+		if existing.blocks(appointment) {
+			return ErrAppointmentConflict
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) GetAppointment(ctx context.Context, id string) (*Appointment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	appointment, ok := m.appointments[id]
+	if !ok {
+		return nil, ErrAppointmentNotFound
+	}
+	return copyAppointment(appointment), nil
+}
+
+func (m *MemoryStore) GetAllAppointments(ctx context.Context, input GetAllAppointmentsInput) ([]Appointment, string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var cursor *Appointment
+	if input.Page.StartAfter != "" {
+		startAfter, err := pagination.DecodeCursor(input.Page.StartAfter, true)
+		if err != nil {
+			return nil, "", err
+		}
+		cursor = &Appointment{ID: startAfter.ID, Date: startAfter.Date}
+	}
+
+	appointments := make([]Appointment, 0, len(m.appointments))
+	for _, appointment := range m.appointments {
+		if input.BusinessID != "" && appointment.BusinessID != input.BusinessID {
+			continue
+		}
+		if input.ClientID != "" && appointment.ClientID != input.ClientID {
+			continue
+		}
+		if input.Status != "" && appointment.Status != input.Status {
+			continue
+		}
+		if !input.From.IsZero() && appointment.Date.Before(input.From) {
+			continue
+		}
+		if !input.To.IsZero() && !appointment.Date.Before(input.To) {
+			continue
+		}
+		if cursor != nil && !before(*cursor, appointment, input.Descending) {
+			continue
+		}
+		appointments = append(appointments, *copyAppointment(appointment))
+	}
+	sort.Slice(appointments, func(i, j int) bool {
+		return before(appointments[i], appointments[j], input.Descending)
+	})
+
+	end, more := pagination.PageEnd(len(appointments), input.Page)
+	appointments = appointments[:end]
+	if !more {
+		return appointments, "", nil
+	}
+	last := appointments[end-1]
+	return appointments, pagination.Cursor{ID: last.ID, Date: last.Date}.Encode(), nil
+}
+
+func (m *MemoryStore) GetBusinessAppointmentsBetween(ctx context.Context, businessID string, from, to time.Time) ([]Appointment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var appointments []Appointment
+	for _, appointment := range m.appointments {
+		if appointment.BusinessID != businessID || !appointment.Overlaps(from, to) {
+			continue
+		}
+		appointments = append(appointments, *copyAppointment(appointment))
+	}
+	sort.Slice(appointments, func(i, j int) bool {
+		return before(appointments[i], appointments[j], false)
+	})
+
+	return appointments, nil
+}
+
+func (m *MemoryStore) Create(ctx context.Context, input CreateInput) (*Appointment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	appointment := Appointment{
+		ClientID:   input.ClientID,
+		BusinessID: input.BusinessID,
+		Date:       input.Date,
+		EndDate:    input.EndDate,
+		Status:     StatusPending,
+	}
+	if err := m.checkConflicts(appointment); err != nil {
+		return nil, err
+	}
+
+	appointment.ID = uuid.New().String()
+	m.appointments[appointment.ID] = appointment
+
+	return copyAppointment(appointment), nil
+}
+
+func (m *MemoryStore) UpdateStatus(ctx context.Context, id string, input UpdateStatusInput) (*Appointment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.appointments[id]
+	if !ok {
+		return nil, ErrAppointmentNotFound
+	}
+	appointment := copyAppointment(stored)
+	if _, err := appointment.changeStatus(input); err != nil {
+		return nil, err
+	}
+	m.appointments[id] = *appointment
+
+	return copyAppointment(*appointment), nil
+}
+
+func (m *MemoryStore) Reschedule(ctx context.Context, id string, input RescheduleInput) (*Appointment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.appointments[id]
+	if !ok {
+		return nil, ErrAppointmentNotFound
+	}
+	appointment := copyAppointment(stored)
+	if _, err := appointment.reschedule(input); err != nil {
+		return nil, err
+	}
+	if err := m.checkConflicts(*appointment); err != nil {
+		return nil, err
+	}
+	m.appointments[id] = *appointment
+
+	return copyAppointment(*appointment), nil
+}
+
+func (m *MemoryStore) Delete(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.appointments, id)
+	return nil
+}
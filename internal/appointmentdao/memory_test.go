@@ -0,0 +1,128 @@
+package appointmentdao
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/devduck123/servizio-be/internal/pagination"
+	"github.com/tj/assert"
+)
+
+func TestMemoryStore_Conflicts(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	baseTime := time.Date(2032, 12, 2, 9, 0, 0, 0, time.UTC)
+
+	booked, err := store.Create(ctx, CreateInput{
+		ClientID:   "client1",
+		BusinessID: "google",
+		Date:       baseTime,
+		EndDate:    baseTime.Add(time.Hour),
+	})
+	assert.NoError(t, err)
+
+	_, err = store.Create(ctx, CreateInput{
+		ClientID:   "client2",
+		BusinessID: "google",
+		Date:       baseTime.Add(30 * time.Minute),
+		EndDate:    baseTime.Add(90 * time.Minute),
+	})
+	assert.Equal(t, ErrAppointmentConflict, err)
+
+	other, err := store.Create(ctx, CreateInput{
+		ClientID:   "client2",
+		BusinessID: "google",
+		Date:       baseTime.Add(time.Hour),
+		EndDate:    baseTime.Add(2 * time.Hour),
+	})
+	assert.NoError(t, err)
+
+	_, err = store.Reschedule(ctx, other.ID, RescheduleInput{
+		Date:    baseTime.Add(30 * time.Minute),
+		EndDate: baseTime.Add(90 * time.Minute),
+	})
+	assert.Equal(t, ErrAppointmentConflict, err)
+
+	// a cancelled appointment frees its slot
+	_, err = store.UpdateStatus(ctx, booked.ID, UpdateStatusInput{
+		Status: StatusCancelled,
+		Actor:  ActorClient,
+	})
+	assert.NoError(t, err)
+	rescheduled, err := store.Reschedule(ctx, other.ID, RescheduleInput{
+		Date:    baseTime.Add(30 * time.Minute),
+		EndDate: baseTime.Add(90 * time.Minute),
+	})
+	assert.NoError(t, err)
+	assert.Len(t, rescheduled.Reschedules, 1)
+}
+
+func TestMemoryStore_UpdateStatus(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	appointment, err := store.Create(ctx, CreateInput{
+		ClientID:   "client1",
+		BusinessID: "google",
+	})
+	assert.NoError(t, err)
+
+	_, err = store.UpdateStatus(ctx, appointment.ID, UpdateStatusInput{
+		Status: StatusConfirmed,
+		Actor:  ActorClient,
+	})
+	assert.Equal(t, ErrInvalidTransition, err)
+
+	_, err = store.UpdateStatus(ctx, "notexists", UpdateStatusInput{
+		Status: StatusConfirmed,
+		Actor:  ActorBusiness,
+	})
+	assert.Equal(t, ErrAppointmentNotFound, err)
+
+	gotAppointment, err := store.GetAppointment(ctx, appointment.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusPending, gotAppointment.Status)
+	assert.Empty(t, gotAppointment.StatusHistory)
+}
+
+func TestMemoryStore_GetAllAppointments(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	baseTime := time.Date(2032, 12, 2, 9, 0, 0, 0, time.UTC)
+
+	var ids []string
+	for i := 0; i < 5; i++ {
+		appointment, err := store.Create(ctx, CreateInput{
+			ClientID:   "client1",
+			BusinessID: "google",
+			Date:       baseTime.Add(time.Duration(i) * time.Hour),
+			EndDate:    baseTime.Add(time.Duration(i)*time.Hour + 30*time.Minute),
+		})
+		assert.NoError(t, err)
+		ids = append(ids, appointment.ID)
+	}
+
+	input := GetAllAppointmentsInput{
+		BusinessID: "google",
+		From:       baseTime.Add(time.Hour),
+		Descending: true,
+		Page:       pagination.Input{Limit: 3},
+	}
+	firstPage, next, err := store.GetAllAppointments(ctx, input)
+	assert.NoError(t, err)
+	assert.Len(t, firstPage, 3)
+	assert.Equal(t, ids[4], firstPage[0].ID)
+	assert.Equal(t, pagination.Cursor{ID: ids[2], Date: baseTime.Add(2 * time.Hour)}.Encode(), next)
+
+	input.Page.StartAfter = next
+	secondPage, next, err := store.GetAllAppointments(ctx, input)
+	assert.NoError(t, err)
+	assert.Len(t, secondPage, 1)
+	assert.Equal(t, ids[1], secondPage[0].ID)
+	assert.Empty(t, next)
+
+	input.Page.StartAfter = pagination.Cursor{ID: ids[2]}.Encode()
+	_, _, err = store.GetAllAppointments(ctx, input)
+	assert.Equal(t, pagination.ErrInvalidCursor, err)
+}
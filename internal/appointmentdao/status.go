@@ -0,0 +1,77 @@
+package appointmentdao
+
+import "time"
+
+type Status string
+
+var (
+	StatusPending   Status = "pending"
+	StatusConfirmed Status = "confirmed"
+	StatusCancelled Status = "cancelled"
+	StatusCompleted Status = "completed"
+	StatusNoShow    Status = "no_show"
+)
+
+func (s Status) IsValid() bool {
+	allStatuses := []Status{StatusPending, StatusConfirmed, StatusCancelled, StatusCompleted, StatusNoShow}
+	for _, status := range allStatuses {
+		if s == status {
+			return true
+		}
+	}
+
+	return false
+}
+
+// OccupiesSlot reports whether an appointment with this status still blocks
+// its time for other bookings.
+func (s Status) OccupiesSlot() bool {
+	return s != StatusCancelled
+}
+
+// IsOpen reports whether an appointment with this status can still be
+// rescheduled.
+func (s Status) IsOpen() bool {
+	return s == StatusPending || s == StatusConfirmed
+}
+
+// Actor is the party of an appointment asking for a status change.
+type Actor string
+
+var (
+	ActorBusiness Actor = "business"
+	ActorClient   Actor = "client"
+)
+
+// transitions lists, for every status, which statuses it can move to and
+// which parties are allowed to make that move.
+var transitions = map[Status]map[Status][]Actor{
+	StatusPending: {
+		StatusConfirmed: {ActorBusiness},
+		StatusCancelled: {ActorBusiness, ActorClient},
+	},
+	StatusConfirmed: {
+		StatusCancelled: {ActorBusiness, ActorClient},
+		StatusCompleted: {ActorBusiness},
+		StatusNoShow:    {ActorBusiness},
+	},
+}
+
+// CanTransition reports whether actor may move an appointment from one
+// status to another.
+func CanTransition(from, to Status, actor Actor) bool {
+	for _, allowed := range transitions[from][to] {
+		if actor == allowed {
+			return true
+		}
+	}
+
+	return false
+}
+
+// StatusChange records who moved an appointment to a status and when.
+type StatusChange struct {
+	Status    Status    `json:"status" firestore:"status"`
+	ChangedBy string    `json:"changedBy" firestore:"changedBy"`
+	ChangedAt time.Time `json:"changedAt" firestore:"changedAt"`
+}
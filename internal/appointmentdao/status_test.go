@@ -0,0 +1,31 @@
+package appointmentdao
+
+import (
+	"testing"
+
+	"github.com/tj/assert"
+)
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from, to Status
+		actor    Actor
+		allowed  bool
+	}{
+		{StatusPending, StatusConfirmed, ActorBusiness, true},
+		{StatusPending, StatusConfirmed, ActorClient, false},
+		{StatusPending, StatusCancelled, ActorClient, true},
+		{StatusPending, StatusCompleted, ActorBusiness, false},
+		{StatusConfirmed, StatusCancelled, ActorBusiness, true},
+		{StatusConfirmed, StatusCompleted, ActorBusiness, true},
+		{StatusConfirmed, StatusCompleted, ActorClient, false},
+		{StatusConfirmed, StatusNoShow, ActorBusiness, true},
+		{StatusConfirmed, StatusNoShow, ActorClient, false},
+		{StatusCancelled, StatusConfirmed, ActorBusiness, false},
+		{StatusCompleted, StatusCancelled, ActorClient, false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.allowed, CanTransition(tt.from, tt.to, tt.actor), "%v -> %v by %v", tt.from, tt.to, tt.actor)
+	}
+}
@@ -0,0 +1,23 @@
+package appointmentdao
+
+import (
+	"context"
+	"time"
+)
+
+// Store is implemented by Dao, backed by Firestore, and by MemoryStore.
+type Store interface {
+	GetAppointment(ctx context.Context, id string) (*Appointment, error)
+	GetAllAppointments(ctx context.Context, input GetAllAppointmentsInput) ([]Appointment, string, error)
+	GetBusinessAppointmentsBetween(ctx context.Context, businessID string, from, to time.Time) ([]Appointment, error)
+	Create(ctx context.Context, input CreateInput) (*Appointment, error)
+	UpdateStatus(ctx context.Context, id string, input UpdateStatusInput) (*Appointment, error)
+	Reschedule(ctx context.Context, id string, input RescheduleInput) (*Appointment, error)
+	Delete(ctx context.Context, id string) error
+}
+
+var (
+	_ Store = (*Dao)(nil)
+	_ Store = (*MemoryStore)(nil)
+	_ Store = instrumented{}
+)
@@ -10,13 +10,38 @@ import (
 	"testing"
 
 	firebase "firebase.google.com/go/v4"
+	"github.com/devduck123/servizio-be/internal/authtoken"
 	"github.com/google/uuid"
 	"github.com/tj/assert"
 )
 
+// Secret signs the tokens minted by GetJWT.
+var Secret = []byte("servizio-be-test-secret")
+
+// Verifier returns a verifier that accepts the tokens minted by GetJWT,
+// pass it to server.WithTokenVerifier.
+func Verifier(projectID string) *authtoken.HMAC {
+	return authtoken.NewHMAC(Secret, projectID)
+}
+
+// GetJWT mints a token for a new user with a verified email. It needs no
+// emulator, the server under test must use Verifier.
 func GetJWT(t *testing.T, projectID string) (string, error) {
 	t.Helper()
 
+	return Verifier(projectID).Mint(authtoken.MintInput{
+		UID:            uuid.New().String(),
+		Email:          fmt.Sprintf("test-%v@test.com", uuid.New()),
+		EmailVerified:  true,
+		SignInProvider: "password",
+	})
+}
+
+// GetEmulatorJWT signs up a new user with a verified email against the
+// Auth emulator on localhost:9099 and returns their ID token.
+func GetEmulatorJWT(t *testing.T, projectID string) (string, error) {
+	t.Helper()
+
 	email := fmt.Sprintf("test-%v@test.com", uuid.New())
 	password := "tester"
 
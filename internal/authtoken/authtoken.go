@@ -0,0 +1,63 @@
+package authtoken
+
+import (
+	"context"
+	"sync"
+
+	firebase "firebase.google.com/go/v4"
+	"firebase.google.com/go/v4/auth"
+)
+
+// Verifier checks an ID token and returns its claims. *auth.Client
+// satisfies it, as do Firebase and HMAC.
+type Verifier interface {
+	VerifyIDToken(ctx context.Context, idToken string) (*auth.Token, error)
+}
+
+var (
+	_ Verifier = (*auth.Client)(nil)
+	_ Verifier = (*Firebase)(nil)
+	_ Verifier = (*HMAC)(nil)
+)
+
+// Firebase verifies tokens issued by Firebase Auth, or by the Auth
+// emulator when FIREBASE_AUTH_EMULATOR_HOST is set.
+type Firebase struct {
+	app *firebase.App
+
+	mu     sync.Mutex
+	client *auth.Client
+}
+
+func NewFirebase(app *firebase.App) *Firebase {
+	return &Firebase{
+		app: app,
+	}
+}
+
+// authClient creates the Auth client on first use so that it keeps its
+// cache of Google's public keys between requests.
+func (f *Firebase) authClient(ctx context.Context) (*auth.Client, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.client != nil {
+		return f.client, nil
+	}
+	client, err := f.app.Auth(ctx)
+	if err != nil {
+		return nil, err
+	}
+	f.client = client
+
+	return client, nil
+}
+
+func (f *Firebase) VerifyIDToken(ctx context.Context, idToken string) (*auth.Token, error) {
+	client, err := f.authClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.VerifyIDToken(ctx, idToken)
+}
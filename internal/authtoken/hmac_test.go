@@ -0,0 +1,64 @@
+package authtoken
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tj/assert"
+)
+
+func TestHMAC(t *testing.T) {
+	ctx := context.Background()
+	h := NewHMAC([]byte("secret"), "servizio-be")
+
+	idToken, err := h.Mint(MintInput{
+		UID:            "foo",
+		Email:          "foo@test.com",
+		EmailVerified:  true,
+		SignInProvider: "password",
+		Claims:         map[string]interface{}{"admin": true},
+	})
+	assert.NoError(t, err)
+
+	token, err := h.VerifyIDToken(ctx, idToken)
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", token.Subject)
+	assert.Equal(t, "foo", token.UID)
+	assert.Equal(t, "password", token.Firebase.SignInProvider)
+	assert.Equal(t, true, token.Claims["email_verified"])
+	assert.Equal(t, true, token.Claims["admin"])
+	assert.True(t, token.Expires > time.Now().Unix())
+}
+
+func TestHMAC_Invalid(t *testing.T) {
+	ctx := context.Background()
+	h := NewHMAC([]byte("secret"), "servizio-be")
+
+	valid, err := h.Mint(MintInput{UID: "foo"})
+	assert.NoError(t, err)
+	otherSecret, err := NewHMAC([]byte("other"), "servizio-be").Mint(MintInput{UID: "foo"})
+	assert.NoError(t, err)
+	otherProject, err := NewHMAC([]byte("secret"), "other").Mint(MintInput{UID: "foo"})
+	assert.NoError(t, err)
+	expired, err := h.Mint(MintInput{UID: "foo", ExpiresIn: -time.Minute})
+	assert.NoError(t, err)
+
+	tests := map[string]struct {
+		idToken string
+		err     error
+	}{
+		"empty":         {"", ErrMalformedToken},
+		"not a jwt":     {"foo.bar", ErrMalformedToken},
+		"tampered":      {valid + "x", ErrInvalidSignature},
+		"other secret":  {otherSecret, ErrInvalidSignature},
+		"other project": {otherProject, ErrWrongAudience},
+		"expired":       {expired, ErrTokenExpired},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := h.VerifyIDToken(ctx, tt.idToken)
+			assert.Equal(t, tt.err, err)
+		})
+	}
+}
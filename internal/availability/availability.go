@@ -0,0 +1,129 @@
+package availability
+
+import (
+	"sort"
+	"time"
+
+	"github.com/devduck123/servizio-be/internal/businessdao"
+)
+
+type Interval struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// OpenIntervals returns the parts of [from, to) during which a business with
+// the given opening hours is open, sorted and with adjacent ranges merged.
+func OpenIntervals(hours businessdao.OpeningHours, from, to time.Time) ([]Interval, error) {
+	loc, err := hours.Location()
+	if err != nil {
+		return nil, err
+	}
+
+	var intervals []Interval
+	local := from.In(loc)
+	day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	for day.Before(to) {
+		ranges := hours.RangesOn(day.Weekday())
+		for _, tr := range ranges {
+			startOffset, endOffset, err := tr.Bounds()
+			if err != nil {
+				return nil, err
+			}
+
+			interval := Interval{
+				Start: atOffset(day, startOffset),
+				End:   atOffset(day, endOffset),
+			}
+			if interval.Start.Before(from) {
+				interval.Start = from
+			}
+			if interval.End.After(to) {
+				interval.End = to
+			}
+			if interval.Start.Before(interval.End) {
+				intervals = append(intervals, interval)
+			}
+		}
+
+		day = time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, loc)
+	}
+
+	return merge(intervals), nil
+}
+
+// atOffset returns the wall-clock time offset from midnight of day, so that
+// 09:00 stays 09:00 on days with a daylight saving transition.
+func atOffset(day time.Time, offset time.Duration) time.Time {
+	hours := int(offset / time.Hour)
+	minutes := int((offset % time.Hour) / time.Minute)
+	return time.Date(day.Year(), day.Month(), day.Day(), hours, minutes, 0, 0, day.Location())
+}
+
+func merge(intervals []Interval) []Interval {
+	sort.Slice(intervals, func(i, j int) bool {
+		return intervals[i].Start.Before(intervals[j].Start)
+	})
+
+	merged := make([]Interval, 0, len(intervals))
+	for _, interval := range intervals {
+		last := len(merged) - 1
+		if last >= 0 && !interval.Start.After(merged[last].End) {
+			if interval.End.After(merged[last].End) {
+				merged[last].End = interval.End
+			}
+			continue
+		}
+		merged = append(merged, interval)
+	}
+
+	return merged
+}
+
+// Within reports whether [start, end) lies entirely inside the opening hours.
+func Within(hours businessdao.OpeningHours, start, end time.Time) (bool, error) {
+	intervals, err := OpenIntervals(hours, start, end)
+	if err != nil {
+		return false, err
+	}
+
+	return len(intervals) == 1 && intervals[0].Start.Equal(start) && intervals[0].End.Equal(end), nil
+}
+
+// FreeSlots removes the busy intervals from the open ones and splits what is
+// left into consecutive slots of the given duration.
+func FreeSlots(open, busy []Interval, duration time.Duration) []Interval {
+	busy = merge(append([]Interval(nil), busy...))
+
+	slots := []Interval{}
+	for _, interval := range open {
+		cursor := interval.Start
+		for _, b := range busy {
+			if !b.End.After(cursor) || !b.Start.Before(interval.End) {
+				continue
+			}
+			slots = appendSlots(slots, cursor, b.Start, duration)
+			if b.End.After(cursor) {
+				cursor = b.End
+			}
+		}
+		slots = appendSlots(slots, cursor, interval.End, duration)
+	}
+
+	return slots
+}
+
+func appendSlots(slots []Interval, start, end time.Time, duration time.Duration) []Interval {
+	if duration <= 0 {
+		return slots
+	}
+
+	for slotStart := start; !slotStart.Add(duration).After(end); slotStart = slotStart.Add(duration) {
+		slots = append(slots, Interval{
+			Start: slotStart,
+			End:   slotStart.Add(duration),
+		})
+	}
+
+	return slots
+}
@@ -0,0 +1,121 @@
+package availability
+
+import (
+	"testing"
+	"time"
+
+	"github.com/devduck123/servizio-be/internal/businessdao"
+	"github.com/tj/assert"
+)
+
+func testHours() businessdao.OpeningHours {
+	return businessdao.OpeningHours{
+		TimeZone: "America/New_York",
+		Weekly: map[string][]businessdao.TimeRange{
+			"monday": {
+				{Start: "09:00", End: "12:00"},
+				{Start: "13:00", End: "17:00"},
+			},
+			"tuesday": {
+				{Start: "09:00", End: "17:00"},
+			},
+		},
+	}
+}
+
+func TestOpenIntervals(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	assert.NoError(t, err)
+
+	// monday 2023-04-17 through wednesday 2023-04-19
+	from := time.Date(2023, 4, 17, 0, 0, 0, 0, loc)
+	to := time.Date(2023, 4, 20, 0, 0, 0, 0, loc)
+	intervals, err := OpenIntervals(testHours(), from, to)
+	assert.NoError(t, err)
+	assert.Equal(t, []Interval{
+		{Start: time.Date(2023, 4, 17, 9, 0, 0, 0, loc), End: time.Date(2023, 4, 17, 12, 0, 0, 0, loc)},
+		{Start: time.Date(2023, 4, 17, 13, 0, 0, 0, loc), End: time.Date(2023, 4, 17, 17, 0, 0, 0, loc)},
+		{Start: time.Date(2023, 4, 18, 9, 0, 0, 0, loc), End: time.Date(2023, 4, 18, 17, 0, 0, 0, loc)},
+	}, intervals)
+}
+
+func TestOpenIntervals_Clipped(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	assert.NoError(t, err)
+
+	from := time.Date(2023, 4, 17, 10, 30, 0, 0, loc)
+	to := time.Date(2023, 4, 17, 14, 0, 0, 0, loc)
+	intervals, err := OpenIntervals(testHours(), from.UTC(), to.UTC())
+	assert.NoError(t, err)
+	assert.Len(t, intervals, 2)
+	assert.True(t, intervals[0].Start.Equal(from))
+	assert.True(t, intervals[1].End.Equal(to))
+}
+
+func TestOpenIntervals_DaylightSaving(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	assert.NoError(t, err)
+
+	hours := businessdao.OpeningHours{
+		TimeZone: "America/New_York",
+		Weekly: map[string][]businessdao.TimeRange{
+			"sunday": {{Start: "09:00", End: "10:00"}},
+		},
+	}
+	// clocks moved forward on 2023-03-12
+	from := time.Date(2023, 3, 12, 0, 0, 0, 0, loc)
+	intervals, err := OpenIntervals(hours, from, from.Add(24*time.Hour))
+	assert.NoError(t, err)
+	assert.Len(t, intervals, 1)
+	assert.Equal(t, 9, intervals[0].Start.In(loc).Hour())
+}
+
+func TestWithin(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	assert.NoError(t, err)
+
+	start := time.Date(2023, 4, 17, 9, 0, 0, 0, loc)
+	within, err := Within(testHours(), start, start.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.True(t, within)
+
+	// spans the lunch break
+	start = time.Date(2023, 4, 17, 11, 30, 0, 0, loc)
+	within, err = Within(testHours(), start, start.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.False(t, within)
+
+	// closed on wednesdays
+	start = time.Date(2023, 4, 19, 9, 0, 0, 0, loc)
+	within, err = Within(testHours(), start, start.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.False(t, within)
+}
+
+func TestFreeSlots(t *testing.T) {
+	start := time.Date(2023, 4, 17, 9, 0, 0, 0, time.UTC)
+	open := []Interval{
+		{Start: start, End: start.Add(3 * time.Hour)},
+	}
+	busy := []Interval{
+		{Start: start.Add(30 * time.Minute), End: start.Add(90 * time.Minute)},
+		{Start: start.Add(time.Hour), End: start.Add(2 * time.Hour)},
+	}
+
+	slots := FreeSlots(open, busy, 30*time.Minute)
+	assert.Equal(t, []Interval{
+		{Start: start, End: start.Add(30 * time.Minute)},
+		{Start: start.Add(2 * time.Hour), End: start.Add(150 * time.Minute)},
+		{Start: start.Add(150 * time.Minute), End: start.Add(3 * time.Hour)},
+	}, slots)
+}
+
+func TestFreeSlots_TooShort(t *testing.T) {
+	start := time.Date(2023, 4, 17, 9, 0, 0, 0, time.UTC)
+	open := []Interval{
+		{Start: start, End: start.Add(45 * time.Minute)},
+	}
+
+	slots := FreeSlots(open, nil, time.Hour)
+	assert.Empty(t, slots)
+}
@@ -0,0 +1,147 @@
+package bootstrap
+
+import (
+	"context"
+	"net/http"
+	"os"
+
+	"cloud.google.com/go/firestore"
+	firebase "firebase.google.com/go/v4"
+	"github.com/devduck123/servizio-be/internal/appointmentdao"
+	"github.com/devduck123/servizio-be/internal/authtoken"
+	"github.com/devduck123/servizio-be/internal/businessdao"
+	"github.com/devduck123/servizio-be/internal/clientdao"
+	"github.com/devduck123/servizio-be/internal/config"
+	"github.com/devduck123/servizio-be/internal/images"
+	"github.com/devduck123/servizio-be/internal/logging"
+	"github.com/devduck123/servizio-be/internal/metrics"
+	"github.com/devduck123/servizio-be/internal/observe"
+	"github.com/devduck123/servizio-be/internal/server"
+	"github.com/devduck123/servizio-be/internal/tracing"
+)
+
+// App is the wired up server.
+type App struct {
+	Handler http.Handler
+	// MetricsHandler serves Prometheus metrics, it is kept off Handler so
+	// that they are only reachable on an internal port
+	MetricsHandler http.Handler
+	// closers release the clients created by Build
+	closers []func() error
+}
+
+// Close releases the clients created by Build, like the Firestore client.
+func (a *App) Close() error {
+	var firstErr error
+	for _, closeFn := range a.closers {
+		if err := closeFn(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// emulatorHosts are where `firebase emulators:start` listens, see firebase.json.
+var emulatorHosts = map[string]string{
+	"FIRESTORE_EMULATOR_HOST":     "localhost:8080",
+	"FIREBASE_AUTH_EMULATOR_HOST": "localhost:9099",
+	"STORAGE_EMULATOR_HOST":       "localhost:9199",
+}
+
+// Build creates the stores, the Firebase app and the server described by
+// cfg, and routes every endpoint on the returned handler.
+func Build(ctx context.Context, cfg config.Config) (*App, error) {
+	app := &App{}
+
+	level, err := logging.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		return nil, err
+	}
+	logging.SetDefault(logging.New(os.Stdout, level))
+
+	if cfg.Local {
+		for name, host := range emulatorHosts {
+			if err := os.Setenv(name, host); err != nil {
+				return nil, err
+			}
+			logging.Default().Info("connecting to emulator", "env", name, "host", host)
+		}
+	}
+
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing)
+	if err != nil {
+		return nil, err
+	}
+	app.closers = append(app.closers, func() error {
+		return shutdownTracing(context.Background())
+	})
+
+	m := metrics.New()
+
+	var (
+		businessDao    businessdao.Store
+		clientDao      clientdao.Store
+		appointmentDao appointmentdao.Store
+		im             images.Store
+	)
+	if cfg.Memory {
+		logging.Default().Info("keeping all data in memory")
+		businessDao = businessdao.Instrument(businessdao.NewMemoryStore(), tracing.ObserveStore)
+		clientDao = clientdao.Instrument(clientdao.NewMemoryStore(), tracing.ObserveStore)
+		appointmentDao = appointmentdao.Instrument(appointmentdao.NewMemoryStore(), tracing.ObserveStore)
+		im = images.NewMemoryStore()
+	} else {
+		fsClient, err := firestore.NewClient(ctx, cfg.ProjectID)
+		if err != nil {
+			app.Close()
+			return nil, err
+		}
+		app.closers = append(app.closers, fsClient.Close)
+
+		observeStore := observe.Chain(m.ObserveStore, tracing.ObserveStore)
+		businessDao = businessdao.Instrument(businessdao.NewDao(fsClient, cfg.Collections.Businesses), observeStore)
+		clientDao = clientdao.Instrument(clientdao.NewDao(fsClient, cfg.Collections.Clients), observeStore)
+		appointmentDao = appointmentdao.Instrument(appointmentdao.NewDao(fsClient, cfg.Collections.Appointments), observeStore)
+		im = &images.ImageManager{
+			BucketName: cfg.BucketName,
+		}
+	}
+
+	firebaseApp, err := firebase.NewApp(ctx, &firebase.Config{
+		ProjectID: cfg.ProjectID,
+	})
+	if err != nil {
+		app.Close()
+		return nil, err
+	}
+
+	opts := []server.Option{
+		server.WithMetrics(m),
+		server.WithMaxImageBytes(cfg.MaxImageBytes),
+		server.WithAuthPolicy(server.AuthPolicy{
+			Providers:            cfg.Auth.Providers,
+			RequireEmailVerified: cfg.Auth.RequireEmailVerified,
+			RequireBearer:        cfg.Auth.RequireBearer,
+		}),
+	}
+	if cfg.AuthSecret != "" {
+		logging.Default().Warn("accepting tokens signed with the auth secret, do not use in production")
+		opts = append(opts, server.WithTokenVerifier(authtoken.NewHMAC([]byte(cfg.AuthSecret), cfg.ProjectID)))
+	}
+
+	s := server.NewServer(businessDao, clientDao, appointmentDao, im, firebaseApp, opts...)
+
+	sm := http.NewServeMux()
+	sm.Handle("/businesses/", s.CORS(s.Trace("/businesses/", s.Logger(s.Metrics("/businesses/", s.BusinessRouter)))))
+	sm.Handle("/clients/", s.CORS(s.Trace("/clients/", s.Logger(s.Metrics("/clients/", s.ClientRouter)))))
+	sm.Handle("/appointments/", s.CORS(s.Trace("/appointments/", s.Logger(s.Metrics("/appointments/", s.AppointmentRouter)))))
+	sm.Handle("/admin/", s.CORS(s.Trace("/admin/", s.Logger(s.Metrics("/admin/", s.AdminRouter)))))
+	sm.Handle("/images/", s.CORS(s.Trace("/images/", s.Logger(s.Metrics("/images/", s.ImageRouter)))))
+	app.Handler = sm
+
+	metricsMux := http.NewServeMux()
+	metricsMux.Handle("/metrics", m.Handler())
+	app.MetricsHandler = metricsMux
+
+	return app, nil
+}
@@ -0,0 +1,51 @@
+package bootstrap
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/devduck123/servizio-be/internal/config"
+	"github.com/tj/assert"
+)
+
+func TestBuild_Memory(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.Default()
+	cfg.Memory = true
+
+	app, err := Build(ctx, cfg)
+	assert.NoError(t, err)
+	defer app.Close()
+
+	for _, path := range []string{"/businesses/", "/clients/", "/appointments/"} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		app.Handler.ServeHTTP(w, r)
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode, path)
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/admin/users/foo/roles", nil)
+	app.Handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusUnauthorized, w.Result().StatusCode)
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, "/images/foo/bar", nil)
+	app.Handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusUnauthorized, w.Result().StatusCode)
+
+	// metrics are only served apart from the API
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	app.Handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	app.MetricsHandler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	assert.Contains(t, w.Body.String(), `servizio_http_requests_total{code="200",method="GET",route="/businesses/"} 1`)
+	assert.Contains(t, w.Body.String(), `servizio_http_requests_total{code="401",method="POST",route="/admin/"} 1`)
+}
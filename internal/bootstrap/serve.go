@@ -0,0 +1,87 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/devduck123/servizio-be/internal/config"
+	"github.com/devduck123/servizio-be/internal/logging"
+)
+
+// NewHTTPServer returns a server for handler with the timeouts in cfg.
+func NewHTTPServer(handler http.Handler, cfg config.HTTP) *http.Server {
+	return &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: time.Duration(cfg.ReadHeaderTimeout),
+		ReadTimeout:       time.Duration(cfg.ReadTimeout),
+		WriteTimeout:      time.Duration(cfg.WriteTimeout),
+		IdleTimeout:       time.Duration(cfg.IdleTimeout),
+	}
+}
+
+// Serve serves the app on ln until ctx is done. It then stops accepting
+// connections, waits up to cfg.ShutdownTimeout for in-flight requests and
+// closes the app, so a deploy does not cut off a booking halfway.
+func (a *App) Serve(ctx context.Context, ln net.Listener, cfg config.HTTP) error {
+	srv := NewHTTPServer(a.Handler, cfg)
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- srv.Serve(ln)
+	}()
+
+	var err error
+	select {
+	case err = <-serveErr:
+		// the server stopped on its own, there is nothing to drain
+	case <-ctx.Done():
+		logging.Default().Info("shutting down, waiting for in-flight requests", "timeout", time.Duration(cfg.ShutdownTimeout))
+		shutdownCtx := context.Background()
+		if cfg.ShutdownTimeout > 0 {
+			var cancel context.CancelFunc
+			shutdownCtx, cancel = context.WithTimeout(shutdownCtx, time.Duration(cfg.ShutdownTimeout))
+			defer cancel()
+		}
+		err = srv.Shutdown(shutdownCtx)
+		if err != nil {
+			// give up on the requests still running
+			srv.Close()
+		}
+		if serveErr := <-serveErr; !errors.Is(serveErr, http.ErrServerClosed) && err == nil {
+			err = serveErr
+		}
+	}
+
+	if closeErr := a.Close(); closeErr != nil && err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// ServeMetrics serves the metrics of the app on ln until ctx is done. It is
+// meant for a port only reachable from inside, such as by a Prometheus
+// sidecar.
+func (a *App) ServeMetrics(ctx context.Context, ln net.Listener, cfg config.HTTP) error {
+	srv := NewHTTPServer(a.MetricsHandler, cfg)
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- srv.Serve(ln)
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+		// scrapes are short, there is nothing worth draining
+		srv.Close()
+		if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	}
+}
@@ -0,0 +1,165 @@
+package bootstrap
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/devduck123/servizio-be/internal/config"
+	"github.com/tj/assert"
+)
+
+// slowApp is an app whose only handler blocks until release is closed.
+func slowApp(started chan<- struct{}, release <-chan struct{}, closed *bool) *App {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.Write([]byte("booked"))
+	})
+	return &App{
+		Handler: handler,
+		closers: []func() error{func() error {
+			*closed = true
+			return nil
+		}},
+	}
+}
+
+func TestServe_DrainsInFlightRequests(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	url := "http://" + ln.Addr().String()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var closed bool
+	app := slowApp(started, release, &closed)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- app.Serve(ctx, ln, config.Default().HTTP)
+	}()
+
+	type result struct {
+		status int
+		body   string
+		err    error
+	}
+	inFlight := make(chan result, 1)
+	go func() {
+		resp, err := http.Get(url)
+		if err != nil {
+			inFlight <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		inFlight <- result{status: resp.StatusCode, body: string(body), err: err}
+	}()
+	<-started
+
+	// SIGTERM arrives while the request is running
+	cancel()
+
+	// new connections are refused once the server starts draining
+	assert.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp", ln.Addr().String())
+		if err != nil {
+			return true
+		}
+		conn.Close()
+		return false
+	}, time.Second, 10*time.Millisecond)
+
+	select {
+	case err := <-serveErr:
+		t.Fatalf("serve returned before the in-flight request finished: %v", err)
+	default:
+	}
+	assert.False(t, closed)
+
+	close(release)
+	res := <-inFlight
+	assert.NoError(t, res.err)
+	assert.Equal(t, http.StatusOK, res.status)
+	assert.Equal(t, "booked", res.body)
+
+	assert.NoError(t, <-serveErr)
+	assert.True(t, closed)
+}
+
+func TestServe_ShutdownTimeout(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	url := "http://" + ln.Addr().String()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	var closed bool
+	app := slowApp(started, release, &closed)
+
+	cfg := config.Default().HTTP
+	cfg.ShutdownTimeout = config.Duration(50 * time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- app.Serve(ctx, ln, cfg)
+	}()
+
+	go http.Get(url)
+	<-started
+	cancel()
+
+	select {
+	case err := <-serveErr:
+		assert.Equal(t, context.DeadlineExceeded, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("serve did not give up on the stuck request")
+	}
+	assert.True(t, closed)
+}
+
+func TestServeMetrics(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	var closed bool
+	app := &App{
+		MetricsHandler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("metrics"))
+		}),
+		closers: []func() error{func() error {
+			closed = true
+			return nil
+		}},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- app.ServeMetrics(ctx, ln, config.Default().HTTP)
+	}()
+
+	resp, err := http.Get("http://" + ln.Addr().String() + "/metrics")
+	assert.NoError(t, err)
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.NoError(t, err)
+	assert.Equal(t, "metrics", string(body))
+
+	cancel()
+	select {
+	case err := <-serveErr:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("ServeMetrics did not return")
+	}
+	// closing the app is left to Serve
+	assert.False(t, closed)
+}
@@ -5,18 +5,50 @@ import (
 	"errors"
 
 	"cloud.google.com/go/firestore"
+	"github.com/devduck123/servizio-be/internal/images"
+	"github.com/devduck123/servizio-be/internal/pagination"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
-var ErrBusinessNotFound = errors.New("business not found")
+var (
+	ErrBusinessNotFound = errors.New("business not found")
+	ErrInvalidCover     = errors.New("cover must be one of the images")
+)
 
 type Business struct {
-	ID       string   `json:"id" firestore:"-"`
-	Name     string   `json:"name" firestore:"name"`
-	Images   []string `json:"images,omitempty" firestore:"images,omitempty"`
-	Category Category `json:"category" firestore:"category"`
-	UserID   string   `json:"userId" firestore:"userId"`
+	ID     string       `json:"id" firestore:"-"`
+	Name   string       `json:"name" firestore:"name"`
+	Images []images.Ref `json:"images,omitempty" firestore:"images,omitempty"`
+	// CoverImage is the key of the image shown first, one of Images
+	CoverImage string   `json:"coverImage,omitempty" firestore:"coverImage,omitempty"`
+	Category   Category `json:"category" firestore:"category"`
+	UserID     string   `json:"userId" firestore:"userId"`
+	// OpeningHours is optional, a business without it can be booked anytime
+	OpeningHours *OpeningHours `json:"openingHours,omitempty" firestore:"openingHours,omitempty"`
+}
+
+// businessDocument is how a business is read from Firestore, its images
+// are decoded by images.RefsFromFirestore as they may be legacy URLs.
+type businessDocument struct {
+	Business
+	Images []interface{} `firestore:"images,omitempty"`
+}
+
+func businessFromSnapshot(snapshot *firestore.DocumentSnapshot) (Business, error) {
+	var document businessDocument
+	if err := snapshot.DataTo(&document); err != nil {
+		return Business{}, err
+	}
+	refs, err := images.RefsFromFirestore(document.Images)
+	if err != nil {
+		return Business{}, err
+	}
+
+	business := document.Business
+	business.ID = snapshot.Ref.ID
+	business.Images = refs
+	return business, nil
 }
 
 type Dao struct {
@@ -40,54 +72,62 @@ func (dao *Dao) GetBusiness(ctx context.Context, id string) (*Business, error) {
 		}
 		return nil, err
 	}
-	var business Business
-	if err := snapshot.DataTo(&business); err != nil {
+	business, err := businessFromSnapshot(snapshot)
+	if err != nil {
 		return nil, err
 	}
-
-	business.ID = docRef.ID
 	return &business, nil
 }
 
 type GetAllBusinessesInput struct {
 	// category is optional
 	Category Category
+	// UserID is optional, it filters by the owning user
+	UserID string
+	Page   pagination.Input
 }
 
-func (dao *Dao) GetAllBusinesses(ctx context.Context, input GetAllBusinessesInput) ([]Business, error) {
-	query := dao.fsClient.Collection(dao.businessCollectionName).Query
+// GetAllBusinesses returns a page of businesses ordered by ID and the
+// cursor to start the next page after, empty on the last page.
+func (dao *Dao) GetAllBusinesses(ctx context.Context, input GetAllBusinessesInput) ([]Business, string, error) {
+	collection := dao.fsClient.Collection(dao.businessCollectionName)
+	query := collection.OrderBy(firestore.DocumentID, firestore.Asc)
 	if input.Category != "" {
 		query = query.Where("category", "==", input.Category)
 	}
-	snapshots, err := query.Documents(ctx).GetAll()
+	if input.UserID != "" {
+		query = query.Where("userId", "==", input.UserID)
+	}
+	snapshots, next, err := pagination.GetPage(ctx, query, input.Page, "")
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	businesses := make([]Business, 0, len(snapshots))
 	for _, snapshot := range snapshots {
-		var business Business
-		if err := snapshot.DataTo(&business); err != nil {
-			return nil, err
+		business, err := businessFromSnapshot(snapshot)
+		if err != nil {
+			return nil, "", err
 		}
-		business.ID = snapshot.Ref.ID
 		businesses = append(businesses, business)
 	}
 
-	return businesses, nil
+	return businesses, next, nil
 }
 
 type CreateInput struct {
-	Name     string
-	Category Category
-	UserID   string
+	Name         string
+	Category     Category
+	UserID       string
+	OpeningHours *OpeningHours
 }
 
 func (dao *Dao) Create(ctx context.Context, input CreateInput) (*Business, error) {
 	business := Business{
-		Name:     input.Name,
-		Category: input.Category,
-		UserID:   input.UserID,
+		Name:         input.Name,
+		Category:     input.Category,
+		UserID:       input.UserID,
+		OpeningHours: input.OpeningHours,
 	}
 
 	doc, _, err := dao.fsClient.Collection(dao.businessCollectionName).Add(ctx, business)
@@ -98,6 +138,39 @@ func (dao *Dao) Create(ctx context.Context, input CreateInput) (*Business, error
 	return &business, nil
 }
 
+// UpdateInput holds the fields to change, nil fields are left as they are.
+type UpdateInput struct {
+	Name         *string
+	Category     *Category
+	OpeningHours *OpeningHours
+}
+
+func (dao *Dao) Update(ctx context.Context, id string, input UpdateInput) (*Business, error) {
+	var updates []firestore.Update
+	if input.Name != nil {
+		updates = append(updates, firestore.Update{Path: "name", Value: *input.Name})
+	}
+	if input.Category != nil {
+		updates = append(updates, firestore.Update{Path: "category", Value: *input.Category})
+	}
+	if input.OpeningHours != nil {
+		updates = append(updates, firestore.Update{Path: "openingHours", Value: *input.OpeningHours})
+	}
+
+	if len(updates) > 0 {
+		docRef := dao.fsClient.Collection(dao.businessCollectionName).Doc(id)
+		_, err := docRef.Update(ctx, updates)
+		if err != nil {
+			if status.Code(err) == codes.NotFound {
+				return nil, ErrBusinessNotFound
+			}
+			return nil, err
+		}
+	}
+
+	return dao.GetBusiness(ctx, id)
+}
+
 func (dao *Dao) Delete(ctx context.Context, id string) error {
 	docRef := dao.fsClient.Collection(dao.businessCollectionName).Doc(id)
 	_, err := docRef.Delete(ctx)
@@ -107,12 +180,12 @@ func (dao *Dao) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
-func (dao *Dao) AppendImage(ctx context.Context, id string, imageURL string) error {
+func (dao *Dao) AppendImage(ctx context.Context, id string, image images.Ref) error {
 	// update business in firestore
 	docRef := dao.fsClient.Collection(dao.businessCollectionName).Doc(id)
 	_, err := docRef.Update(ctx, []firestore.Update{{
 		Path:  "images",
-		Value: firestore.ArrayUnion(imageURL),
+		Value: firestore.ArrayUnion(image),
 	}})
 	if err != nil {
 		return err
@@ -120,3 +193,89 @@ func (dao *Dao) AppendImage(ctx context.Context, id string, imageURL string) err
 
 	return nil
 }
+
+// RemoveImage removes the image with the given key from a business, and as
+// its cover if it was. It returns images.ErrImageNotFound if the business
+// has no such image.
+func (dao *Dao) RemoveImage(ctx context.Context, id string, key string) error {
+	docRef := dao.fsClient.Collection(dao.businessCollectionName).Doc(id)
+	return dao.fsClient.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		business, err := dao.getInTransaction(tx, docRef)
+		if err != nil {
+			return err
+		}
+
+		remaining, ok := images.RemoveRef(business.Images, key)
+		if !ok {
+			return images.ErrImageNotFound
+		}
+		updates := []firestore.Update{{Path: "images", Value: remaining}}
+		if business.CoverImage == key {
+			updates = append(updates, firestore.Update{Path: "coverImage", Value: firestore.Delete})
+		}
+		return tx.Update(docRef, updates)
+	})
+}
+
+// OrderImages puts the images of a business in the order of keys, which
+// must list every image once, and makes cover its cover image. An empty
+// cover leaves the business without one.
+func (dao *Dao) OrderImages(ctx context.Context, id string, keys []string, cover string) (*Business, error) {
+	docRef := dao.fsClient.Collection(dao.businessCollectionName).Doc(id)
+	err := dao.fsClient.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		business, err := dao.getInTransaction(tx, docRef)
+		if err != nil {
+			return err
+		}
+
+		ordered, err := orderImages(business.Images, keys, cover)
+		if err != nil {
+			return err
+		}
+		updates := []firestore.Update{{Path: "images", Value: ordered}}
+		if cover == "" {
+			updates = append(updates, firestore.Update{Path: "coverImage", Value: firestore.Delete})
+		} else {
+			updates = append(updates, firestore.Update{Path: "coverImage", Value: cover})
+		}
+		return tx.Update(docRef, updates)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return dao.GetBusiness(ctx, id)
+}
+
+func (dao *Dao) getInTransaction(tx *firestore.Transaction, docRef *firestore.DocumentRef) (*Business, error) {
+	snapshot, err := tx.Get(docRef)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, ErrBusinessNotFound
+		}
+		return nil, err
+	}
+	business, err := businessFromSnapshot(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	return &business, nil
+}
+
+// orderImages checks a new order and cover of refs, shared by Dao and
+// MemoryStore.
+func orderImages(refs []images.Ref, keys []string, cover string) ([]images.Ref, error) {
+	ordered, err := images.OrderRefs(refs, keys)
+	if err != nil {
+		return nil, err
+	}
+	if cover == "" {
+		return ordered, nil
+	}
+	for _, ref := range ordered {
+		if ref.Key == cover {
+			return ordered, nil
+		}
+	}
+	return nil, ErrInvalidCover
+}
@@ -8,6 +8,8 @@ import (
 	"testing"
 
 	"github.com/devduck123/servizio-be/internal/firestoretest"
+	"github.com/devduck123/servizio-be/internal/images"
+	"github.com/devduck123/servizio-be/internal/pagination"
 	"github.com/google/uuid"
 	"github.com/tj/assert"
 )
@@ -79,11 +81,34 @@ func TestGetAllBusinesses_ByCategory(t *testing.T) {
 	getAllBusinessesInput := GetAllBusinessesInput{
 		Category: CategoryAutomotive,
 	}
-	allBusinesses, err := dao.GetAllBusinesses(ctx, getAllBusinessesInput)
+	allBusinesses, _, err := dao.GetAllBusinesses(ctx, getAllBusinessesInput)
 	assert.NoError(t, err)
 	assert.Len(t, allBusinesses, 1)
 }
 
+func TestGetAllBusinesses_ByUser(t *testing.T) {
+	ctx := context.Background()
+	dao := createTestDao(ctx, t)
+
+	_, err := dao.Create(ctx, CreateInput{
+		Name:   "foo",
+		UserID: "owner",
+	})
+	assert.NoError(t, err)
+	_, err = dao.Create(ctx, CreateInput{
+		Name:   "bar",
+		UserID: "someone else",
+	})
+	assert.NoError(t, err)
+
+	allBusinesses, _, err := dao.GetAllBusinesses(ctx, GetAllBusinessesInput{
+		UserID: "owner",
+	})
+	assert.NoError(t, err)
+	assert.Len(t, allBusinesses, 1)
+	assert.Equal(t, "foo", allBusinesses[0].Name)
+}
+
 func TestGetAllBusinesses(t *testing.T) {
 	ctx := context.Background()
 	dao := createTestDao(ctx, t)
@@ -102,7 +127,7 @@ func TestGetAllBusinesses(t *testing.T) {
 	assert.NoError(t, err)
 
 	getAllBusinessesInput := GetAllBusinessesInput{}
-	allBusinesses, err := dao.GetAllBusinesses(ctx, getAllBusinessesInput)
+	allBusinesses, _, err := dao.GetAllBusinesses(ctx, getAllBusinessesInput)
 	assert.NoError(t, err)
 	assert.Len(t, allBusinesses, 2)
 }
@@ -150,12 +175,136 @@ func TestAppendImage(t *testing.T) {
 	business, err := dao.Create(ctx, input)
 	assert.NoError(t, err)
 
-	dao.AppendImage(ctx, business.ID, "test1")
-	dao.AppendImage(ctx, business.ID, "test2")
-	dao.AppendImage(ctx, business.ID, "test3")
+	dao.AppendImage(ctx, business.ID, images.Ref{Key: "test1", Variants: map[string]string{"128": "test1_128"}})
+	dao.AppendImage(ctx, business.ID, images.Ref{Key: "test2", Variants: map[string]string{"128": "test2_128"}})
+	dao.AppendImage(ctx, business.ID, images.Ref{Key: "test3", Variants: map[string]string{"128": "test3_128"}})
 
 	business, err = dao.GetBusiness(ctx, business.ID)
 	assert.NoError(t, err)
 
 	assert.Equal(t, 3, len(business.Images))
 }
+
+func TestOrderAndRemoveImages(t *testing.T) {
+	ctx := context.Background()
+	dao := createTestDao(ctx, t)
+
+	business, err := dao.Create(ctx, CreateInput{Name: "foo"})
+	assert.NoError(t, err)
+	for _, key := range []string{"test1", "test2", "test3"} {
+		assert.NoError(t, dao.AppendImage(ctx, business.ID, images.Ref{Key: key}))
+	}
+
+	business, err = dao.OrderImages(ctx, business.ID, []string{"test3", "test1", "test2"}, "test1")
+	assert.NoError(t, err)
+	assert.Equal(t, []images.Ref{{Key: "test3"}, {Key: "test1"}, {Key: "test2"}}, business.Images)
+	assert.Equal(t, "test1", business.CoverImage)
+
+	_, err = dao.OrderImages(ctx, business.ID, []string{"test3", "test3", "test2"}, "")
+	assert.Equal(t, images.ErrInvalidOrder, err)
+	_, err = dao.OrderImages(ctx, business.ID, []string{"test3", "test1", "test2"}, "test4")
+	assert.Equal(t, ErrInvalidCover, err)
+	_, err = dao.OrderImages(ctx, "notexists", nil, "")
+	assert.Equal(t, ErrBusinessNotFound, err)
+
+	assert.NoError(t, dao.RemoveImage(ctx, business.ID, "test1"))
+	assert.Equal(t, images.ErrImageNotFound, dao.RemoveImage(ctx, business.ID, "test1"))
+
+	business, err = dao.GetBusiness(ctx, business.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []images.Ref{{Key: "test3"}, {Key: "test2"}}, business.Images)
+	assert.Empty(t, business.CoverImage)
+}
+
+func TestLegacyImageURLs(t *testing.T) {
+	ctx := context.Background()
+	dao := createTestDao(ctx, t)
+
+	// businesses written before image keys were kept hold their URLs
+	docRef := dao.fsClient.Collection(dao.businessCollectionName).NewDoc()
+	_, err := docRef.Set(ctx, map[string]interface{}{
+		"name":   "foo",
+		"images": []interface{}{"servizio-be.appspot.com/foo/test1", "servizio-be.appspot.com/foo/test2"},
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, dao.AppendImage(ctx, docRef.ID, images.Ref{Key: "foo/test3"}))
+
+	want := []images.Ref{{Key: "foo/test1"}, {Key: "foo/test2"}, {Key: "foo/test3"}}
+	business, err := dao.GetBusiness(ctx, docRef.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, want, business.Images)
+	businesses, _, err := dao.GetAllBusinesses(ctx, GetAllBusinessesInput{})
+	assert.NoError(t, err)
+	assert.Len(t, businesses, 1)
+	assert.Equal(t, want, businesses[0].Images)
+
+	business, err = dao.OrderImages(ctx, docRef.ID, []string{"foo/test2", "foo/test1", "foo/test3"}, "")
+	assert.NoError(t, err)
+	assert.Equal(t, []images.Ref{{Key: "foo/test2"}, {Key: "foo/test1"}, {Key: "foo/test3"}}, business.Images)
+	assert.NoError(t, dao.RemoveImage(ctx, docRef.ID, "foo/test1"))
+}
+
+func TestUpdateBusiness(t *testing.T) {
+	ctx := context.Background()
+	dao := createTestDao(ctx, t)
+
+	business, err := dao.Create(ctx, CreateInput{
+		Name:     "foo",
+		Category: CategoryPets,
+	})
+	assert.NoError(t, err)
+
+	name := "bar"
+	updated, err := dao.Update(ctx, business.ID, UpdateInput{
+		Name: &name,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "bar", updated.Name)
+	assert.Equal(t, CategoryPets, updated.Category)
+}
+
+func TestUpdateBusiness_NotExists(t *testing.T) {
+	ctx := context.Background()
+	dao := createTestDao(ctx, t)
+
+	name := "bar"
+	_, err := dao.Update(ctx, "foo", UpdateInput{
+		Name: &name,
+	})
+	assert.Equal(t, ErrBusinessNotFound, err)
+}
+
+func TestGetAllBusinesses_Pages(t *testing.T) {
+	ctx := context.Background()
+	dao := createTestDao(ctx, t)
+
+	for _, name := range []string{"foo", "bar", "baz"} {
+		_, err := dao.Create(ctx, CreateInput{
+			Name: name,
+		})
+		assert.NoError(t, err)
+	}
+
+	firstPage, next, err := dao.GetAllBusinesses(ctx, GetAllBusinessesInput{
+		Page: pagination.Input{Limit: 2},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, firstPage, 2)
+	assert.Equal(t, pagination.Cursor{ID: firstPage[1].ID}.Encode(), next)
+
+	// the page after a business still follows once it is deleted
+	assert.NoError(t, dao.Delete(ctx, firstPage[1].ID))
+	secondPage, next, err := dao.GetAllBusinesses(ctx, GetAllBusinessesInput{
+		Page: pagination.Input{Limit: 2, StartAfter: next},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, secondPage, 1)
+	assert.Empty(t, next)
+	assert.NotEqual(t, firstPage[0].ID, secondPage[0].ID)
+	assert.NotEqual(t, firstPage[1].ID, secondPage[0].ID)
+
+	_, _, err = dao.GetAllBusinesses(ctx, GetAllBusinessesInput{
+		Page: pagination.Input{StartAfter: "invalid"},
+	})
+	assert.Equal(t, pagination.ErrInvalidCursor, err)
+}
@@ -0,0 +1,113 @@
+package businessdao
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// TimeRange is a span of wall-clock time within a day, formatted as "15:04".
+// An End of "24:00" means the range lasts until midnight.
+type TimeRange struct {
+	Start string `json:"start" firestore:"start"`
+	End   string `json:"end" firestore:"end"`
+}
+
+// Bounds returns the start and end of the range as offsets from midnight.
+func (tr TimeRange) Bounds() (time.Duration, time.Duration, error) {
+	start, err := parseClock(tr.Start)
+	if err != nil {
+		return 0, 0, err
+	}
+	end, err := parseClock(tr.End)
+	if err != nil {
+		return 0, 0, err
+	}
+	if end <= start {
+		return 0, 0, fmt.Errorf("range %v-%v must end after it starts", tr.Start, tr.End)
+	}
+
+	return start, end, nil
+}
+
+func parseClock(clock string) (time.Duration, error) {
+	if clock == "24:00" {
+		return 24 * time.Hour, nil
+	}
+
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", clock)
+	}
+
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// OpeningHours holds the weekly schedule of a business. Weekly is keyed by
+// lowercase weekday name ("monday", "tuesday", ...) and a day without an
+// entry is closed. Times are interpreted in TimeZone, an IANA zone name.
+type OpeningHours struct {
+	TimeZone string                 `json:"timeZone" firestore:"timeZone"`
+	Weekly   map[string][]TimeRange `json:"weekly" firestore:"weekly"`
+}
+
+func (oh OpeningHours) Validate() error {
+	if _, err := oh.Location(); err != nil {
+		return err
+	}
+
+	for day, ranges := range oh.Weekly {
+		if _, ok := parseWeekday(day); !ok {
+			return fmt.Errorf("invalid weekday %q", day)
+		}
+
+		type bounds struct{ start, end time.Duration }
+		all := make([]bounds, 0, len(ranges))
+		for _, tr := range ranges {
+			start, end, err := tr.Bounds()
+			if err != nil {
+				return fmt.Errorf("%v: %w", day, err)
+			}
+			all = append(all, bounds{start, end})
+		}
+
+		sort.Slice(all, func(i, j int) bool { return all[i].start < all[j].start })
+		for i := 1; i < len(all); i++ {
+			if all[i].start < all[i-1].end {
+				return fmt.Errorf("%v: opening hours overlap", day)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (oh OpeningHours) Location() (*time.Location, error) {
+	if oh.TimeZone == "" {
+		return nil, errors.New("timeZone cannot be empty")
+	}
+
+	loc, err := time.LoadLocation(oh.TimeZone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timeZone %q", oh.TimeZone)
+	}
+
+	return loc, nil
+}
+
+// RangesOn returns the opening ranges for the given weekday.
+func (oh OpeningHours) RangesOn(weekday time.Weekday) []TimeRange {
+	return oh.Weekly[strings.ToLower(weekday.String())]
+}
+
+func parseWeekday(day string) (time.Weekday, bool) {
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		if day == strings.ToLower(weekday.String()) {
+			return weekday, true
+		}
+	}
+
+	return 0, false
+}
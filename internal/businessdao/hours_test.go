@@ -0,0 +1,51 @@
+package businessdao
+
+import (
+	"testing"
+
+	"github.com/tj/assert"
+)
+
+func TestOpeningHoursValidate(t *testing.T) {
+	hours := OpeningHours{
+		TimeZone: "Europe/Rome",
+		Weekly: map[string][]TimeRange{
+			"monday":   {{Start: "09:00", End: "12:30"}, {Start: "14:00", End: "18:00"}},
+			"saturday": {{Start: "10:00", End: "24:00"}},
+		},
+	}
+	assert.NoError(t, hours.Validate())
+}
+
+func TestOpeningHoursValidate_Invalid(t *testing.T) {
+	tests := map[string]OpeningHours{
+		"missing time zone": {
+			Weekly: map[string][]TimeRange{"monday": {{Start: "09:00", End: "17:00"}}},
+		},
+		"unknown time zone": {
+			TimeZone: "Mars/Olympus",
+		},
+		"unknown weekday": {
+			TimeZone: "UTC",
+			Weekly:   map[string][]TimeRange{"funday": {{Start: "09:00", End: "17:00"}}},
+		},
+		"malformed time": {
+			TimeZone: "UTC",
+			Weekly:   map[string][]TimeRange{"monday": {{Start: "9am", End: "17:00"}}},
+		},
+		"ends before start": {
+			TimeZone: "UTC",
+			Weekly:   map[string][]TimeRange{"monday": {{Start: "17:00", End: "09:00"}}},
+		},
+		"overlapping ranges": {
+			TimeZone: "UTC",
+			Weekly:   map[string][]TimeRange{"monday": {{Start: "09:00", End: "13:00"}, {Start: "12:00", End: "17:00"}}},
+		},
+	}
+
+	for name, hours := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Error(t, hours.Validate())
+		})
+	}
+}
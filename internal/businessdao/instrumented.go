@@ -0,0 +1,89 @@
+package businessdao
+
+import (
+	"context"
+
+	"github.com/devduck123/servizio-be/internal/images"
+	"github.com/devduck123/servizio-be/internal/observe"
+	"github.com/devduck123/servizio-be/internal/pagination"
+)
+
+const storeName = "businessdao"
+
+// expectedErrors are answers of the store rather than failures of it.
+var expectedErrors = []error{
+	ErrBusinessNotFound,
+	ErrInvalidCover,
+	images.ErrImageNotFound,
+	images.ErrInvalidOrder,
+	pagination.ErrInvalidCursor,
+}
+
+// instrumented reports every call of the wrapped store to observe.
+type instrumented struct {
+	store   Store
+	observe observe.Func
+}
+
+// Instrument wraps store so that fn sees every call, bootstrap uses it to
+// count the calls made to Firestore. fn sees the errors in expectedErrors
+// as nil.
+func Instrument(store Store, fn observe.Func) Store {
+	return instrumented{store: store, observe: fn}
+}
+
+func (i instrumented) GetBusiness(ctx context.Context, id string) (*Business, error) {
+	ctx, done := i.observe(ctx, storeName, "GetBusiness")
+	business, err := i.store.GetBusiness(ctx, id)
+	done(observe.Failure(err, expectedErrors...))
+	return business, err
+}
+
+func (i instrumented) GetAllBusinesses(ctx context.Context, input GetAllBusinessesInput) ([]Business, string, error) {
+	ctx, done := i.observe(ctx, storeName, "GetAllBusinesses")
+	businesses, next, err := i.store.GetAllBusinesses(ctx, input)
+	done(observe.Failure(err, expectedErrors...))
+	return businesses, next, err
+}
+
+func (i instrumented) Create(ctx context.Context, input CreateInput) (*Business, error) {
+	ctx, done := i.observe(ctx, storeName, "Create")
+	business, err := i.store.Create(ctx, input)
+	done(observe.Failure(err, expectedErrors...))
+	return business, err
+}
+
+func (i instrumented) Update(ctx context.Context, id string, input UpdateInput) (*Business, error) {
+	ctx, done := i.observe(ctx, storeName, "Update")
+	business, err := i.store.Update(ctx, id, input)
+	done(observe.Failure(err, expectedErrors...))
+	return business, err
+}
+
+func (i instrumented) Delete(ctx context.Context, id string) error {
+	ctx, done := i.observe(ctx, storeName, "Delete")
+	err := i.store.Delete(ctx, id)
+	done(observe.Failure(err, expectedErrors...))
+	return err
+}
+
+func (i instrumented) AppendImage(ctx context.Context, id string, image images.Ref) error {
+	ctx, done := i.observe(ctx, storeName, "AppendImage")
+	err := i.store.AppendImage(ctx, id, image)
+	done(observe.Failure(err, expectedErrors...))
+	return err
+}
+
+func (i instrumented) RemoveImage(ctx context.Context, id string, key string) error {
+	ctx, done := i.observe(ctx, storeName, "RemoveImage")
+	err := i.store.RemoveImage(ctx, id, key)
+	done(observe.Failure(err, expectedErrors...))
+	return err
+}
+
+func (i instrumented) OrderImages(ctx context.Context, id string, keys []string, cover string) (*Business, error) {
+	ctx, done := i.observe(ctx, storeName, "OrderImages")
+	business, err := i.store.OrderImages(ctx, id, keys, cover)
+	done(observe.Failure(err, expectedErrors...))
+	return business, err
+}
@@ -0,0 +1,68 @@
+package businessdao
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/devduck123/servizio-be/internal/metrics"
+	"github.com/tj/assert"
+)
+
+func TestInstrument(t *testing.T) {
+	ctx := context.Background()
+
+	type call struct {
+		store, method string
+		err           error
+	}
+	var calls []call
+	store := Instrument(NewMemoryStore(), func(ctx context.Context, store, method string) (context.Context, func(err error)) {
+		return ctx, func(err error) {
+			calls = append(calls, call{store, method, err})
+		}
+	})
+
+	business, err := store.Create(ctx, CreateInput{Name: "foo", Category: CategoryPets, UserID: "bar"})
+	assert.NoError(t, err)
+	_, err = store.GetBusiness(ctx, "missing")
+	assert.Equal(t, ErrBusinessNotFound, err)
+	assert.NoError(t, store.Delete(ctx, business.ID))
+
+	assert.Equal(t, []call{
+		{"businessdao", "Create", nil},
+		// not found is an answer, not a failure of the store
+		{"businessdao", "GetBusiness", nil},
+		{"businessdao", "Delete", nil},
+	}, calls)
+}
+
+func TestInstrument_NotFoundIsNotAnError(t *testing.T) {
+	ctx := context.Background()
+	m := metrics.New()
+	store := Instrument(failingStore{NewMemoryStore()}, m.ObserveStore)
+
+	_, err := store.GetBusiness(ctx, "missing")
+	assert.Equal(t, ErrBusinessNotFound, err)
+	_, err = store.GetBusiness(ctx, "fail")
+	assert.Error(t, err)
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Contains(t, w.Body.String(), `servizio_firestore_calls_total{method="GetBusiness",store="businessdao"} 2`)
+	assert.Contains(t, w.Body.String(), `servizio_firestore_errors_total{method="GetBusiness",store="businessdao"} 1`)
+}
+
+// failingStore fails to get the business with the ID "fail".
+type failingStore struct {
+	*MemoryStore
+}
+
+func (s failingStore) GetBusiness(ctx context.Context, id string) (*Business, error) {
+	if id == "fail" {
+		return nil, errors.New("deadline exceeded")
+	}
+	return s.MemoryStore.GetBusiness(ctx, id)
+}
@@ -0,0 +1,184 @@
+package businessdao
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/devduck123/servizio-be/internal/images"
+	"github.com/devduck123/servizio-be/internal/pagination"
+	"github.com/google/uuid"
+)
+
+// MemoryStore keeps businesses in memory, for tests and for running the
+// server without Firestore.
+type MemoryStore struct {
+	mu         sync.Mutex
+	businesses map[string]Business
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		businesses: make(map[string]Business),
+	}
+}
+
+// copyBusiness keeps callers from modifying the stored images.
+func copyBusiness(business Business) *Business {
+	business.Images = images.CopyRefs(business.Images)
+	return &business
+}
+
+func (m *MemoryStore) GetBusiness(ctx context.Context, id string) (*Business, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	business, ok := m.businesses[id]
+	if !ok {
+		return nil, ErrBusinessNotFound
+	}
+	return copyBusiness(business), nil
+}
+
+func (m *MemoryStore) GetAllBusinesses(ctx context.Context, input GetAllBusinessesInput) ([]Business, string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var cursor pagination.Cursor
+	if input.Page.StartAfter != "" {
+		var err error
+		cursor, err = pagination.DecodeCursor(input.Page.StartAfter, false)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	businesses := make([]Business, 0, len(m.businesses))
+	for _, business := range m.businesses {
+		if input.Category != "" && business.Category != input.Category {
+			continue
+		}
+		if input.UserID != "" && business.UserID != input.UserID {
+			continue
+		}
+		if business.ID <= cursor.ID {
+			continue
+		}
+		businesses = append(businesses, *copyBusiness(business))
+	}
+	sort.Slice(businesses, func(i, j int) bool {
+		return businesses[i].ID < businesses[j].ID
+	})
+
+	end, more := pagination.PageEnd(len(businesses), input.Page)
+	businesses = businesses[:end]
+	if !more {
+		return businesses, "", nil
+	}
+	return businesses, pagination.Cursor{ID: businesses[end-1].ID}.Encode(), nil
+}
+
+func (m *MemoryStore) Create(ctx context.Context, input CreateInput) (*Business, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	business := Business{
+		ID:           uuid.New().String(),
+		Name:         input.Name,
+		Category:     input.Category,
+		UserID:       input.UserID,
+		OpeningHours: input.OpeningHours,
+	}
+	m.businesses[business.ID] = business
+
+	return copyBusiness(business), nil
+}
+
+func (m *MemoryStore) Update(ctx context.Context, id string, input UpdateInput) (*Business, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	business, ok := m.businesses[id]
+	if !ok {
+		return nil, ErrBusinessNotFound
+	}
+	if input.Name != nil {
+		business.Name = *input.Name
+	}
+	if input.Category != nil {
+		business.Category = *input.Category
+	}
+	if input.OpeningHours != nil {
+		business.OpeningHours = input.OpeningHours
+	}
+	m.businesses[id] = business
+
+	return copyBusiness(business), nil
+}
+
+func (m *MemoryStore) Delete(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.businesses, id)
+	return nil
+}
+
+func (m *MemoryStore) AppendImage(ctx context.Context, id string, image images.Ref) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	business, ok := m.businesses[id]
+	if !ok {
+		return ErrBusinessNotFound
+	}
+	for _, stored := range business.Images {
+		if stored.Key == image.Key {
+			return nil
+		}
+	}
+	business.Images = append(business.Images, images.CopyRefs([]images.Ref{image})...)
+	m.businesses[id] = business
+
+	return nil
+}
+
+func (m *MemoryStore) RemoveImage(ctx context.Context, id string, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	business, ok := m.businesses[id]
+	if !ok {
+		return ErrBusinessNotFound
+	}
+	remaining, ok := images.RemoveRef(business.Images, key)
+	if !ok {
+		return images.ErrImageNotFound
+	}
+	business.Images = remaining
+	if business.CoverImage == key {
+		business.CoverImage = ""
+	}
+	m.businesses[id] = business
+
+	return nil
+}
+
+func (m *MemoryStore) OrderImages(ctx context.Context, id string, keys []string, cover string) (*Business, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	business, ok := m.businesses[id]
+	if !ok {
+		return nil, ErrBusinessNotFound
+	}
+	ordered, err := orderImages(business.Images, keys, cover)
+	if err != nil {
+		return nil, err
+	}
+	business.Images = images.CopyRefs(ordered)
+	business.CoverImage = cover
+	m.businesses[id] = business
+
+	return copyBusiness(business), nil
+}
@@ -0,0 +1,106 @@
+package businessdao
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/devduck123/servizio-be/internal/images"
+	"github.com/devduck123/servizio-be/internal/pagination"
+	"github.com/tj/assert"
+)
+
+func TestMemoryStore_GetAllBusinesses(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	for _, name := range []string{"foo", "bar", "baz"} {
+		_, err := store.Create(ctx, CreateInput{
+			Name:     name,
+			Category: CategoryPets,
+		})
+		assert.NoError(t, err)
+	}
+	_, err := store.Create(ctx, CreateInput{
+		Name:     "qux",
+		Category: CategoryAutomotive,
+	})
+	assert.NoError(t, err)
+
+	input := GetAllBusinessesInput{
+		Category: CategoryPets,
+		Page:     pagination.Input{Limit: 2},
+	}
+	firstPage, next, err := store.GetAllBusinesses(ctx, input)
+	assert.NoError(t, err)
+	assert.Len(t, firstPage, 2)
+	assert.Equal(t, pagination.Cursor{ID: firstPage[1].ID}.Encode(), next)
+
+	// the page after a business still follows once it is deleted
+	assert.NoError(t, store.Delete(ctx, firstPage[1].ID))
+	input.Page.StartAfter = next
+	secondPage, next, err := store.GetAllBusinesses(ctx, input)
+	assert.NoError(t, err)
+	assert.Len(t, secondPage, 1)
+	assert.True(t, secondPage[0].ID > firstPage[1].ID)
+	assert.Empty(t, next)
+
+	for _, cursor := range []string{"deleted", pagination.Cursor{ID: "foo", Date: time.Now()}.Encode()} {
+		input.Page.StartAfter = cursor
+		_, _, err = store.GetAllBusinesses(ctx, input)
+		assert.Equal(t, pagination.ErrInvalidCursor, err)
+	}
+}
+
+func TestMemoryStore_Update(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	business, err := store.Create(ctx, CreateInput{
+		Name:     "foo",
+		Category: CategoryPets,
+	})
+	assert.NoError(t, err)
+
+	category := CategoryHome
+	updated, err := store.Update(ctx, business.ID, UpdateInput{
+		Category: &category,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", updated.Name)
+	assert.Equal(t, CategoryHome, updated.Category)
+
+	_, err = store.Update(ctx, "notexists", UpdateInput{})
+	assert.Equal(t, ErrBusinessNotFound, err)
+}
+
+func TestMemoryStore_Images(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	business, err := store.Create(ctx, CreateInput{Name: "foo"})
+	assert.NoError(t, err)
+	for _, key := range []string{"a", "b", "c"} {
+		assert.NoError(t, store.AppendImage(ctx, business.ID, images.Ref{Key: key}))
+	}
+
+	updated, err := store.OrderImages(ctx, business.ID, []string{"c", "a", "b"}, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, []images.Ref{{Key: "c"}, {Key: "a"}, {Key: "b"}}, updated.Images)
+	assert.Equal(t, "a", updated.CoverImage)
+
+	_, err = store.OrderImages(ctx, business.ID, []string{"c", "a"}, "")
+	assert.Equal(t, images.ErrInvalidOrder, err)
+	_, err = store.OrderImages(ctx, business.ID, []string{"c", "a", "b"}, "d")
+	assert.Equal(t, ErrInvalidCover, err)
+
+	// removing the cover unsets it
+	assert.NoError(t, store.RemoveImage(ctx, business.ID, "a"))
+	assert.Equal(t, images.ErrImageNotFound, store.RemoveImage(ctx, business.ID, "a"))
+	assert.Equal(t, ErrBusinessNotFound, store.RemoveImage(ctx, "notexists", "a"))
+
+	updated, err = store.GetBusiness(ctx, business.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []images.Ref{{Key: "c"}, {Key: "b"}}, updated.Images)
+	assert.Empty(t, updated.CoverImage)
+}
@@ -0,0 +1,25 @@
+package businessdao
+
+import (
+	"context"
+
+	"github.com/devduck123/servizio-be/internal/images"
+)
+
+// Store is implemented by Dao, backed by Firestore, and by MemoryStore.
+type Store interface {
+	GetBusiness(ctx context.Context, id string) (*Business, error)
+	GetAllBusinesses(ctx context.Context, input GetAllBusinessesInput) ([]Business, string, error)
+	Create(ctx context.Context, input CreateInput) (*Business, error)
+	Update(ctx context.Context, id string, input UpdateInput) (*Business, error)
+	Delete(ctx context.Context, id string) error
+	AppendImage(ctx context.Context, id string, image images.Ref) error
+	RemoveImage(ctx context.Context, id string, key string) error
+	OrderImages(ctx context.Context, id string, keys []string, cover string) (*Business, error)
+}
+
+var (
+	_ Store = (*Dao)(nil)
+	_ Store = (*MemoryStore)(nil)
+	_ Store = instrumented{}
+)
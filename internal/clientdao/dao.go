@@ -5,17 +5,46 @@ import (
 	"errors"
 
 	"cloud.google.com/go/firestore"
+	"github.com/devduck123/servizio-be/internal/images"
+	"github.com/devduck123/servizio-be/internal/pagination"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
-var ErrClientNotFound = errors.New("client not found")
+var (
+	ErrClientNotFound = errors.New("client not found")
+	ErrClientExists   = errors.New("user already has a client profile")
+)
 
 type Client struct {
-	ID        string   `json:"id" firestore:"-"`
-	FirstName string   `json:"firstName" firestore:"firstName"`
-	LastName  string   `json:"lastName" firestore:"lastName"`
-	Images    []string `json:"images,omitempty" firestore:"images,omitempty"`
+	ID        string       `json:"id" firestore:"-"`
+	FirstName string       `json:"firstName" firestore:"firstName"`
+	LastName  string       `json:"lastName" firestore:"lastName"`
+	Images    []images.Ref `json:"images,omitempty" firestore:"images,omitempty"`
+	UserID    string       `json:"userId" firestore:"userId"`
+}
+
+// clientDocument is how a client is read from Firestore, its images are
+// decoded by images.RefsFromFirestore as they may be legacy URLs.
+type clientDocument struct {
+	Client
+	Images []interface{} `firestore:"images,omitempty"`
+}
+
+func clientFromSnapshot(snapshot *firestore.DocumentSnapshot) (Client, error) {
+	var document clientDocument
+	if err := snapshot.DataTo(&document); err != nil {
+		return Client{}, err
+	}
+	refs, err := images.RefsFromFirestore(document.Images)
+	if err != nil {
+		return Client{}, err
+	}
+
+	client := document.Client
+	client.ID = snapshot.Ref.ID
+	client.Images = refs
+	return client, nil
 }
 
 type Dao struct {
@@ -39,33 +68,55 @@ func (dao *Dao) GetClient(ctx context.Context, id string) (*Client, error) {
 		}
 		return nil, err
 	}
-	var client Client
-	if err := snapshot.DataTo(&client); err != nil {
+	client, err := clientFromSnapshot(snapshot)
+	if err != nil {
 		return nil, err
 	}
-
-	client.ID = docRef.ID
 	return &client, nil
 }
 
-func (dao *Dao) GetAllClients(ctx context.Context) ([]Client, error) {
-	query := dao.fsClient.Collection(dao.clientCollectionName).Query
-	snapshots, err := query.Documents(ctx).GetAll()
+type GetAllClientsInput struct {
+	Page pagination.Input
+}
+
+// GetAllClients returns a page of clients ordered by ID and the cursor to
+// start the next page after, empty on the last page.
+func (dao *Dao) GetAllClients(ctx context.Context, input GetAllClientsInput) ([]Client, string, error) {
+	collection := dao.fsClient.Collection(dao.clientCollectionName)
+	query := collection.OrderBy(firestore.DocumentID, firestore.Asc)
+	snapshots, next, err := pagination.GetPage(ctx, query, input.Page, "")
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	clients := make([]Client, 0, len(snapshots))
 	for _, snapshot := range snapshots {
-		var client Client
-		if err := snapshot.DataTo(&client); err != nil {
-			return nil, err
+		client, err := clientFromSnapshot(snapshot)
+		if err != nil {
+			return nil, "", err
 		}
-		client.ID = snapshot.Ref.ID
 		clients = append(clients, client)
 	}
 
-	return clients, nil
+	return clients, next, nil
+}
+
+// GetClientByUserID returns the client profile owned by the given user.
+func (dao *Dao) GetClientByUserID(ctx context.Context, userID string) (*Client, error) {
+	query := dao.fsClient.Collection(dao.clientCollectionName).Where("userId", "==", userID).Limit(1)
+	snapshots, err := query.Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(snapshots) == 0 {
+		return nil, ErrClientNotFound
+	}
+
+	client, err := clientFromSnapshot(snapshots[0])
+	if err != nil {
+		return nil, err
+	}
+	return &client, nil
 }
 
 type CreateInput struct {
@@ -74,20 +125,67 @@ type CreateInput struct {
 	UserID    string
 }
 
+// Create stores a new client, returning ErrClientExists if the user
+// already has a client profile.
 func (dao *Dao) Create(ctx context.Context, input CreateInput) (*Client, error) {
 	client := Client{
 		FirstName: input.FirstName,
 		LastName:  input.LastName,
+		UserID:    input.UserID,
 	}
 
-	doc, _, err := dao.fsClient.Collection(dao.clientCollectionName).Add(ctx, client)
+	collection := dao.fsClient.Collection(dao.clientCollectionName)
+	docRef := collection.NewDoc()
+	err := dao.fsClient.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		if client.UserID != "" {
+			query := collection.Where("userId", "==", client.UserID).Limit(1)
+			snapshots, err := tx.Documents(query).GetAll()
+			if err != nil {
+				return err
+			}
+			if len(snapshots) > 0 {
+				return ErrClientExists
+			}
+		}
+
+		return tx.Create(docRef, client)
+	})
 	if err != nil {
 		return nil, err
 	}
-	client.ID = doc.ID
+	client.ID = docRef.ID
 	return &client, nil
 }
 
+// UpdateInput holds the fields to change, nil fields are left as they are.
+type UpdateInput struct {
+	FirstName *string
+	LastName  *string
+}
+
+func (dao *Dao) Update(ctx context.Context, id string, input UpdateInput) (*Client, error) {
+	var updates []firestore.Update
+	if input.FirstName != nil {
+		updates = append(updates, firestore.Update{Path: "firstName", Value: *input.FirstName})
+	}
+	if input.LastName != nil {
+		updates = append(updates, firestore.Update{Path: "lastName", Value: *input.LastName})
+	}
+
+	if len(updates) > 0 {
+		docRef := dao.fsClient.Collection(dao.clientCollectionName).Doc(id)
+		_, err := docRef.Update(ctx, updates)
+		if err != nil {
+			if status.Code(err) == codes.NotFound {
+				return nil, ErrClientNotFound
+			}
+			return nil, err
+		}
+	}
+
+	return dao.GetClient(ctx, id)
+}
+
 func (dao *Dao) Delete(ctx context.Context, id string) error {
 	docRef := dao.fsClient.Collection(dao.clientCollectionName).Doc(id)
 	_, err := docRef.Delete(ctx)
@@ -97,12 +195,12 @@ func (dao *Dao) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
-func (dao *Dao) AppendImage(ctx context.Context, id string, key string) error {
+func (dao *Dao) AppendImage(ctx context.Context, id string, image images.Ref) error {
 	// update client in firestore
 	docRef := dao.fsClient.Collection(dao.clientCollectionName).Doc(id)
 	_, err := docRef.Update(ctx, []firestore.Update{{
 		Path:  "images",
-		Value: firestore.ArrayUnion(key),
+		Value: firestore.ArrayUnion(image),
 	}})
 	if err != nil {
 		return err
@@ -110,3 +208,28 @@ func (dao *Dao) AppendImage(ctx context.Context, id string, key string) error {
 
 	return nil
 }
+
+// RemoveImage removes the image with the given key from a client. It
+// returns images.ErrImageNotFound if the client has no such image.
+func (dao *Dao) RemoveImage(ctx context.Context, id string, key string) error {
+	docRef := dao.fsClient.Collection(dao.clientCollectionName).Doc(id)
+	return dao.fsClient.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		snapshot, err := tx.Get(docRef)
+		if err != nil {
+			if status.Code(err) == codes.NotFound {
+				return ErrClientNotFound
+			}
+			return err
+		}
+		client, err := clientFromSnapshot(snapshot)
+		if err != nil {
+			return err
+		}
+
+		remaining, ok := images.RemoveRef(client.Images, key)
+		if !ok {
+			return images.ErrImageNotFound
+		}
+		return tx.Update(docRef, []firestore.Update{{Path: "images", Value: remaining}})
+	})
+}
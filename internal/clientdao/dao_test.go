@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/devduck123/servizio-be/internal/firestoretest"
+	"github.com/devduck123/servizio-be/internal/images"
 	"github.com/google/uuid"
 	"github.com/tj/assert"
 )
@@ -78,7 +79,7 @@ func TestGetAllClients(t *testing.T) {
 	_, err = dao.Create(ctx, createInput2)
 	assert.NoError(t, err)
 
-	allClients, err := dao.GetAllClients(ctx)
+	allClients, _, err := dao.GetAllClients(ctx, GetAllClientsInput{})
 	assert.NoError(t, err)
 	assert.Len(t, allClients, 2)
 }
@@ -131,12 +132,117 @@ func TestAppendImage(t *testing.T) {
 	client, err := dao.Create(ctx, input)
 	assert.NoError(t, err)
 
-	dao.AppendImage(ctx, client.ID, "test1")
-	dao.AppendImage(ctx, client.ID, "test2")
-	dao.AppendImage(ctx, client.ID, "test3")
+	dao.AppendImage(ctx, client.ID, images.Ref{Key: "test1", Variants: map[string]string{"128": "test1_128"}})
+	dao.AppendImage(ctx, client.ID, images.Ref{Key: "test2", Variants: map[string]string{"128": "test2_128"}})
+	dao.AppendImage(ctx, client.ID, images.Ref{Key: "test3", Variants: map[string]string{"128": "test3_128"}})
 
 	client, err = dao.GetClient(ctx, client.ID)
 	assert.NoError(t, err)
 
 	assert.Equal(t, 3, len(client.Images))
 }
+
+func TestRemoveImage(t *testing.T) {
+	ctx := context.Background()
+	dao := createTestDao(ctx, t)
+
+	client, err := dao.Create(ctx, CreateInput{
+		FirstName: "foo",
+		LastName:  "bar",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, dao.AppendImage(ctx, client.ID, images.Ref{Key: "test1"}))
+	assert.NoError(t, dao.AppendImage(ctx, client.ID, images.Ref{Key: "test2"}))
+
+	assert.NoError(t, dao.RemoveImage(ctx, client.ID, "test1"))
+	assert.Equal(t, images.ErrImageNotFound, dao.RemoveImage(ctx, client.ID, "test1"))
+	assert.Equal(t, ErrClientNotFound, dao.RemoveImage(ctx, "notexists", "test1"))
+
+	client, err = dao.GetClient(ctx, client.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []images.Ref{{Key: "test2"}}, client.Images)
+}
+
+func TestLegacyImageURLs(t *testing.T) {
+	ctx := context.Background()
+	dao := createTestDao(ctx, t)
+
+	// clients written before image keys were kept hold their URLs
+	docRef := dao.fsClient.Collection(dao.clientCollectionName).NewDoc()
+	_, err := docRef.Set(ctx, map[string]interface{}{
+		"firstName": "foo",
+		"userId":    "owner",
+		"images":    []interface{}{"servizio-be.appspot.com/foo/test1", "servizio-be.appspot.com/foo/test2"},
+	})
+	assert.NoError(t, err)
+
+	client, err := dao.GetClientByUserID(ctx, "owner")
+	assert.NoError(t, err)
+	assert.Equal(t, []images.Ref{{Key: "foo/test1"}, {Key: "foo/test2"}}, client.Images)
+
+	assert.NoError(t, dao.RemoveImage(ctx, docRef.ID, "foo/test1"))
+	client, err = dao.GetClient(ctx, docRef.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []images.Ref{{Key: "foo/test2"}}, client.Images)
+}
+
+func TestCreateClient_OnePerUser(t *testing.T) {
+	ctx := context.Background()
+	dao := createTestDao(ctx, t)
+
+	input := CreateInput{
+		FirstName: "foo",
+		LastName:  "bar",
+		UserID:    "user",
+	}
+	client, err := dao.Create(ctx, input)
+	assert.NoError(t, err)
+	assert.Equal(t, input.UserID, client.UserID)
+
+	_, err = dao.Create(ctx, input)
+	assert.Equal(t, ErrClientExists, err)
+}
+
+func TestGetClientByUserID(t *testing.T) {
+	ctx := context.Background()
+	dao := createTestDao(ctx, t)
+
+	client, err := dao.Create(ctx, CreateInput{
+		FirstName: "foo",
+		LastName:  "bar",
+		UserID:    "user",
+	})
+	assert.NoError(t, err)
+
+	gotClient, err := dao.GetClientByUserID(ctx, "user")
+	assert.NoError(t, err)
+	assert.Equal(t, client.ID, gotClient.ID)
+	assert.Equal(t, "user", gotClient.UserID)
+
+	_, err = dao.GetClientByUserID(ctx, "nobody")
+	assert.Equal(t, ErrClientNotFound, err)
+}
+
+func TestUpdateClient(t *testing.T) {
+	ctx := context.Background()
+	dao := createTestDao(ctx, t)
+
+	client, err := dao.Create(ctx, CreateInput{
+		FirstName: "foo",
+		LastName:  "bar",
+	})
+	assert.NoError(t, err)
+
+	lastName := "baz"
+	updated, err := dao.Update(ctx, client.ID, UpdateInput{
+		LastName: &lastName,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", updated.FirstName)
+	assert.Equal(t, "baz", updated.LastName)
+
+	_, err = dao.Update(ctx, "nobody", UpdateInput{
+		LastName: &lastName,
+	})
+	assert.Equal(t, ErrClientNotFound, err)
+}
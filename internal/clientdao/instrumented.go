@@ -0,0 +1,88 @@
+package clientdao
+
+import (
+	"context"
+
+	"github.com/devduck123/servizio-be/internal/images"
+	"github.com/devduck123/servizio-be/internal/observe"
+	"github.com/devduck123/servizio-be/internal/pagination"
+)
+
+const storeName = "clientdao"
+
+// expectedErrors are answers of the store rather than failures of it.
+var expectedErrors = []error{
+	ErrClientNotFound,
+	ErrClientExists,
+	images.ErrImageNotFound,
+	pagination.ErrInvalidCursor,
+}
+
+// instrumented reports every call of the wrapped store to observe.
+type instrumented struct {
+	store   Store
+	observe observe.Func
+}
+
+// Instrument wraps store so that fn sees every call, bootstrap uses it to
+// count the calls made to Firestore. fn sees the errors in expectedErrors
+// as nil.
+func Instrument(store Store, fn observe.Func) Store {
+	return instrumented{store: store, observe: fn}
+}
+
+func (i instrumented) GetClient(ctx context.Context, id string) (*Client, error) {
+	ctx, done := i.observe(ctx, storeName, "GetClient")
+	client, err := i.store.GetClient(ctx, id)
+	done(observe.Failure(err, expectedErrors...))
+	return client, err
+}
+
+func (i instrumented) GetClientByUserID(ctx context.Context, userID string) (*Client, error) {
+	ctx, done := i.observe(ctx, storeName, "GetClientByUserID")
+	client, err := i.store.GetClientByUserID(ctx, userID)
+	done(observe.Failure(err, expectedErrors...))
+	return client, err
+}
+
+func (i instrumented) GetAllClients(ctx context.Context, input GetAllClientsInput) ([]Client, string, error) {
+	ctx, done := i.observe(ctx, storeName, "GetAllClients")
+	clients, next, err := i.store.GetAllClients(ctx, input)
+	done(observe.Failure(err, expectedErrors...))
+	return clients, next, err
+}
+
+func (i instrumented) Create(ctx context.Context, input CreateInput) (*Client, error) {
+	ctx, done := i.observe(ctx, storeName, "Create")
+	client, err := i.store.Create(ctx, input)
+	done(observe.Failure(err, expectedErrors...))
+	return client, err
+}
+
+func (i instrumented) Update(ctx context.Context, id string, input UpdateInput) (*Client, error) {
+	ctx, done := i.observe(ctx, storeName, "Update")
+	client, err := i.store.Update(ctx, id, input)
+	done(observe.Failure(err, expectedErrors...))
+	return client, err
+}
+
+func (i instrumented) Delete(ctx context.Context, id string) error {
+	ctx, done := i.observe(ctx, storeName, "Delete")
+	err := i.store.Delete(ctx, id)
+	done(observe.Failure(err, expectedErrors...))
+	return err
+}
+
+func (i instrumented) AppendImage(ctx context.Context, id string, image images.Ref) error {
+	ctx, done := i.observe(ctx, storeName, "AppendImage")
+	err := i.store.AppendImage(ctx, id, image)
+	done(observe.Failure(err, expectedErrors...))
+	return err
+}
+
+func (i instrumented) RemoveImage(ctx context.Context, id string, key string) error {
+	ctx, done := i.observe(ctx, storeName, "RemoveImage")
+	err := i.store.RemoveImage(ctx, id, key)
+	done(observe.Failure(err, expectedErrors...))
+	return err
+}
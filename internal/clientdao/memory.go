@@ -0,0 +1,172 @@
+package clientdao
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/devduck123/servizio-be/internal/images"
+	"github.com/devduck123/servizio-be/internal/pagination"
+	"github.com/google/uuid"
+)
+
+// MemoryStore keeps clients in memory, for tests and for running the
+// server without Firestore.
+type MemoryStore struct {
+	mu      sync.Mutex
+	clients map[string]Client
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		clients: make(map[string]Client),
+	}
+}
+
+// copyClient keeps callers from modifying the stored images.
+func copyClient(client Client) *Client {
+	client.Images = images.CopyRefs(client.Images)
+	return &client
+}
+
+func (m *MemoryStore) GetClient(ctx context.Context, id string) (*Client, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	client, ok := m.clients[id]
+	if !ok {
+		return nil, ErrClientNotFound
+	}
+	return copyClient(client), nil
+}
+
+func (m *MemoryStore) GetClientByUserID(ctx context.Context, userID string) (*Client, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, client := range m.clients {
+		if client.UserID == userID {
+			return copyClient(client), nil
+		}
+	}
+	return nil, ErrClientNotFound
+}
+
+func (m *MemoryStore) GetAllClients(ctx context.Context, input GetAllClientsInput) ([]Client, string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var cursor pagination.Cursor
+	if input.Page.StartAfter != "" {
+		var err error
+		cursor, err = pagination.DecodeCursor(input.Page.StartAfter, false)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	clients := make([]Client, 0, len(m.clients))
+	for _, client := range m.clients {
+		if client.ID <= cursor.ID {
+			continue
+		}
+		clients = append(clients, *copyClient(client))
+	}
+	sort.Slice(clients, func(i, j int) bool {
+		return clients[i].ID < clients[j].ID
+	})
+
+	end, more := pagination.PageEnd(len(clients), input.Page)
+	clients = clients[:end]
+	if !more {
+		return clients, "", nil
+	}
+	return clients, pagination.Cursor{ID: clients[end-1].ID}.Encode(), nil
+}
+
+func (m *MemoryStore) Create(ctx context.Context, input CreateInput) (*Client, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if input.UserID != "" {
+		for _, client := range m.clients {
+			if client.UserID == input.UserID {
+				return nil, ErrClientExists
+			}
+		}
+	}
+
+	client := Client{
+		ID:        uuid.New().String(),
+		FirstName: input.FirstName,
+		LastName:  input.LastName,
+		UserID:    input.UserID,
+	}
+	m.clients[client.ID] = client
+
+	return copyClient(client), nil
+}
+
+func (m *MemoryStore) Update(ctx context.Context, id string, input UpdateInput) (*Client, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	client, ok := m.clients[id]
+	if !ok {
+		return nil, ErrClientNotFound
+	}
+	if input.FirstName != nil {
+		client.FirstName = *input.FirstName
+	}
+	if input.LastName != nil {
+		client.LastName = *input.LastName
+	}
+	m.clients[id] = client
+
+	return copyClient(client), nil
+}
+
+func (m *MemoryStore) Delete(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.clients, id)
+	return nil
+}
+
+func (m *MemoryStore) AppendImage(ctx context.Context, id string, image images.Ref) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	client, ok := m.clients[id]
+	if !ok {
+		return ErrClientNotFound
+	}
+	for _, stored := range client.Images {
+		if stored.Key == image.Key {
+			return nil
+		}
+	}
+	client.Images = append(client.Images, images.CopyRefs([]images.Ref{image})...)
+	m.clients[id] = client
+
+	return nil
+}
+
+func (m *MemoryStore) RemoveImage(ctx context.Context, id string, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	client, ok := m.clients[id]
+	if !ok {
+		return ErrClientNotFound
+	}
+	remaining, ok := images.RemoveRef(client.Images, key)
+	if !ok {
+		return images.ErrImageNotFound
+	}
+	client.Images = remaining
+	m.clients[id] = client
+
+	return nil
+}
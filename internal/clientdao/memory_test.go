@@ -0,0 +1,50 @@
+package clientdao
+
+import (
+	"context"
+	"testing"
+
+	"github.com/devduck123/servizio-be/internal/images"
+	"github.com/tj/assert"
+)
+
+func TestMemoryStore_OnePerUser(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	input := CreateInput{
+		FirstName: "foo",
+		LastName:  "bar",
+		UserID:    "user",
+	}
+	client, err := store.Create(ctx, input)
+	assert.NoError(t, err)
+
+	_, err = store.Create(ctx, input)
+	assert.Equal(t, ErrClientExists, err)
+
+	gotClient, err := store.GetClientByUserID(ctx, "user")
+	assert.NoError(t, err)
+	assert.Equal(t, client.ID, gotClient.ID)
+
+	_, err = store.GetClientByUserID(ctx, "nobody")
+	assert.Equal(t, ErrClientNotFound, err)
+}
+
+func TestMemoryStore_RemoveImage(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	client, err := store.Create(ctx, CreateInput{FirstName: "foo"})
+	assert.NoError(t, err)
+	assert.NoError(t, store.AppendImage(ctx, client.ID, images.Ref{Key: "a"}))
+	assert.NoError(t, store.AppendImage(ctx, client.ID, images.Ref{Key: "b"}))
+
+	assert.NoError(t, store.RemoveImage(ctx, client.ID, "a"))
+	assert.Equal(t, images.ErrImageNotFound, store.RemoveImage(ctx, client.ID, "a"))
+	assert.Equal(t, ErrClientNotFound, store.RemoveImage(ctx, "notexists", "a"))
+
+	updated, err := store.GetClient(ctx, client.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []images.Ref{{Key: "b"}}, updated.Images)
+}
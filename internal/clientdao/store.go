@@ -0,0 +1,25 @@
+package clientdao
+
+import (
+	"context"
+
+	"github.com/devduck123/servizio-be/internal/images"
+)
+
+// Store is implemented by Dao, backed by Firestore, and by MemoryStore.
+type Store interface {
+	GetClient(ctx context.Context, id string) (*Client, error)
+	GetClientByUserID(ctx context.Context, userID string) (*Client, error)
+	GetAllClients(ctx context.Context, input GetAllClientsInput) ([]Client, string, error)
+	Create(ctx context.Context, input CreateInput) (*Client, error)
+	Update(ctx context.Context, id string, input UpdateInput) (*Client, error)
+	Delete(ctx context.Context, id string) error
+	AppendImage(ctx context.Context, id string, image images.Ref) error
+	RemoveImage(ctx context.Context, id string, key string) error
+}
+
+var (
+	_ Store = (*Dao)(nil)
+	_ Store = (*MemoryStore)(nil)
+	_ Store = instrumented{}
+)
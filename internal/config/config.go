@@ -0,0 +1,403 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/devduck123/servizio-be/internal/logging"
+)
+
+type Config struct {
+	ProjectID  string `json:"projectId"`
+	BucketName string `json:"bucketName"`
+	Port       int    `json:"port"`
+	// MetricsPort is where cmd/server serves Prometheus metrics, apart
+	// from the API so they are not public. 0 turns them off.
+	MetricsPort int `json:"metricsPort"`
+	// Local points the clients at the Firebase emulators
+	Local bool `json:"local"`
+	// Memory keeps all data in memory instead of Firestore and Storage
+	Memory bool `json:"memory"`
+	// AuthSecret, if set, accepts tokens minted by cmd/minttoken instead of
+	// Firebase Auth tokens. Never set it in production.
+	AuthSecret  string      `json:"authSecret"`
+	Collections Collections `json:"collections"`
+	Auth        Auth        `json:"auth"`
+	HTTP        HTTP        `json:"http"`
+	// LogLevel is the lowest level logged: debug, info, warn or error
+	LogLevel string  `json:"logLevel"`
+	Tracing  Tracing `json:"tracing"`
+	// MaxImageBytes is the largest image that can be uploaded
+	MaxImageBytes int64 `json:"maxImageBytes"`
+}
+
+type Collections struct {
+	Businesses   string `json:"businesses"`
+	Clients      string `json:"clients"`
+	Appointments string `json:"appointments"`
+}
+
+// Auth is the policy applied to ID tokens, see server.AuthPolicy.
+type Auth struct {
+	Providers            []string `json:"providers"`
+	RequireEmailVerified bool     `json:"requireEmailVerified"`
+	RequireBearer        bool     `json:"requireBearer"`
+}
+
+// Tracing picks where OpenTelemetry spans are exported.
+type Tracing struct {
+	// Exporter is none, stdout or otlp
+	Exporter string `json:"exporter"`
+	// Endpoint is the host:port of the OTLP HTTP collector, empty uses
+	// OTEL_EXPORTER_OTLP_ENDPOINT or localhost:4318
+	Endpoint string `json:"endpoint"`
+	// Insecure sends spans to the collector over plain HTTP
+	Insecure bool `json:"insecure"`
+}
+
+// HTTP holds the timeouts of the http.Server run by cmd/server, zero means
+// no timeout.
+type HTTP struct {
+	ReadHeaderTimeout Duration `json:"readHeaderTimeout"`
+	ReadTimeout       Duration `json:"readTimeout"`
+	WriteTimeout      Duration `json:"writeTimeout"`
+	IdleTimeout       Duration `json:"idleTimeout"`
+	// ShutdownTimeout is how long in-flight requests get to finish after
+	// SIGTERM before their connections are closed
+	ShutdownTimeout Duration `json:"shutdownTimeout"`
+}
+
+// Duration is a time.Duration written as a string like "15s" in the
+// config file.
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(raw []byte) error {
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return err
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+func Default() Config {
+	return Config{
+		ProjectID:   "servizio-be",
+		BucketName:  "servizio-be.appspot.com",
+		Port:        3000,
+		MetricsPort: 9090,
+		Collections: Collections{
+			Businesses:   "businesses",
+			Clients:      "clients",
+			Appointments: "appointments",
+		},
+		Auth: Auth{
+			Providers:            []string{"password", "phone", "google.com"},
+			RequireEmailVerified: true,
+		},
+		LogLevel:      "info",
+		MaxImageBytes: 10 << 20,
+		Tracing: Tracing{
+			Exporter: "none",
+		},
+		HTTP: HTTP{
+			ReadHeaderTimeout: Duration(5 * time.Second),
+			ReadTimeout:       Duration(15 * time.Second),
+			WriteTimeout:      Duration(30 * time.Second),
+			IdleTimeout:       Duration(60 * time.Second),
+			// Cloud Run kills the container 10 seconds after SIGTERM
+			ShutdownTimeout: Duration(10 * time.Second),
+		},
+	}
+}
+
+// setting is a field of Config that can be set by a flag and an env var.
+type setting struct {
+	flag    string
+	env     string
+	usage   string
+	boolean bool
+	set     func(cfg *Config, value string) error
+}
+
+func stringSetting(field func(cfg *Config) *string) func(cfg *Config, value string) error {
+	return func(cfg *Config, value string) error {
+		*field(cfg) = value
+		return nil
+	}
+}
+
+func boolSetting(field func(cfg *Config) *bool) func(cfg *Config, value string) error {
+	return func(cfg *Config, value string) error {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		*field(cfg) = b
+		return nil
+	}
+}
+
+func durationSetting(field func(cfg *Config) *Duration) func(cfg *Config, value string) error {
+	return func(cfg *Config, value string) error {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		*field(cfg) = Duration(d)
+		return nil
+	}
+}
+
+var settings = []setting{
+	{
+		flag: "log-level", env: "SERVIZIO_LOG_LEVEL", usage: "lowest level logged: debug, info, warn or error",
+		set: stringSetting(func(cfg *Config) *string { return &cfg.LogLevel }),
+	},
+	{
+		flag: "project-id", env: "SERVIZIO_PROJECT_ID", usage: "Google Cloud project to use",
+		set: stringSetting(func(cfg *Config) *string { return &cfg.ProjectID }),
+	},
+	{
+		flag: "bucket", env: "SERVIZIO_BUCKET", usage: "Cloud Storage bucket for images",
+		set: stringSetting(func(cfg *Config) *string { return &cfg.BucketName }),
+	},
+	{
+		flag: "port", env: "SERVIZIO_PORT", usage: "port cmd/server listens on",
+		set: func(cfg *Config, value string) error {
+			port, err := strconv.Atoi(value)
+			if err != nil {
+				return err
+			}
+			cfg.Port = port
+			return nil
+		},
+	},
+	{
+		flag: "metrics-port", env: "SERVIZIO_METRICS_PORT", usage: "port cmd/server serves /metrics on, apart from the API, 0 turns metrics off",
+		set: func(cfg *Config, value string) error {
+			port, err := strconv.Atoi(value)
+			if err != nil {
+				return err
+			}
+			cfg.MetricsPort = port
+			return nil
+		},
+	},
+	{
+		flag: "local", env: "SERVIZIO_LOCAL", usage: "local connects to the firebase emulators running locally", boolean: true,
+		set: boolSetting(func(cfg *Config) *bool { return &cfg.Local }),
+	},
+	{
+		flag: "memory", env: "SERVIZIO_MEMORY", usage: "memory keeps all data in memory instead of firestore and storage", boolean: true,
+		set: boolSetting(func(cfg *Config) *bool { return &cfg.Memory }),
+	},
+	{
+		flag: "auth-secret", env: "SERVIZIO_AUTH_SECRET", usage: "accept tokens minted by cmd/minttoken with this secret instead of firebase auth",
+		set: stringSetting(func(cfg *Config) *string { return &cfg.AuthSecret }),
+	},
+	{
+		flag: "businesses-collection", env: "SERVIZIO_BUSINESSES_COLLECTION", usage: "firestore collection of businesses",
+		set: stringSetting(func(cfg *Config) *string { return &cfg.Collections.Businesses }),
+	},
+	{
+		flag: "clients-collection", env: "SERVIZIO_CLIENTS_COLLECTION", usage: "firestore collection of clients",
+		set: stringSetting(func(cfg *Config) *string { return &cfg.Collections.Clients }),
+	},
+	{
+		flag: "appointments-collection", env: "SERVIZIO_APPOINTMENTS_COLLECTION", usage: "firestore collection of appointments",
+		set: stringSetting(func(cfg *Config) *string { return &cfg.Collections.Appointments }),
+	},
+	{
+		flag: "auth-providers", env: "SERVIZIO_AUTH_PROVIDERS", usage: "comma separated sign in providers to accept, empty accepts any",
+		set: func(cfg *Config, value string) error {
+			cfg.Auth.Providers = nil
+			for _, provider := range strings.Split(value, ",") {
+				if provider = strings.TrimSpace(provider); provider != "" {
+					cfg.Auth.Providers = append(cfg.Auth.Providers, provider)
+				}
+			}
+			return nil
+		},
+	},
+	{
+		flag: "require-email-verified", env: "SERVIZIO_REQUIRE_EMAIL_VERIFIED", usage: "reject tokens with an unverified email", boolean: true,
+		set: boolSetting(func(cfg *Config) *bool { return &cfg.Auth.RequireEmailVerified }),
+	},
+	{
+		flag: "require-bearer", env: "SERVIZIO_REQUIRE_BEARER", usage: "reject authorization headers without the Bearer prefix", boolean: true,
+		set: boolSetting(func(cfg *Config) *bool { return &cfg.Auth.RequireBearer }),
+	},
+	{
+		flag: "max-image-bytes", env: "SERVIZIO_MAX_IMAGE_BYTES", usage: "largest image that can be uploaded, in bytes",
+		set: func(cfg *Config, value string) error {
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return err
+			}
+			cfg.MaxImageBytes = n
+			return nil
+		},
+	},
+	{
+		flag: "trace-exporter", env: "SERVIZIO_TRACE_EXPORTER", usage: "where to export traces: none, stdout or otlp",
+		set: stringSetting(func(cfg *Config) *string { return &cfg.Tracing.Exporter }),
+	},
+	{
+		flag: "otlp-endpoint", env: "SERVIZIO_OTLP_ENDPOINT", usage: "host:port of the OTLP HTTP collector traces are exported to",
+		set: stringSetting(func(cfg *Config) *string { return &cfg.Tracing.Endpoint }),
+	},
+	{
+		flag: "otlp-insecure", env: "SERVIZIO_OTLP_INSECURE", usage: "export traces to the collector over plain HTTP", boolean: true,
+		set: boolSetting(func(cfg *Config) *bool { return &cfg.Tracing.Insecure }),
+	},
+	{
+		flag: "read-header-timeout", env: "SERVIZIO_READ_HEADER_TIMEOUT", usage: "time allowed to read request headers",
+		set: durationSetting(func(cfg *Config) *Duration { return &cfg.HTTP.ReadHeaderTimeout }),
+	},
+	{
+		flag: "read-timeout", env: "SERVIZIO_READ_TIMEOUT", usage: "time allowed to read a whole request",
+		set: durationSetting(func(cfg *Config) *Duration { return &cfg.HTTP.ReadTimeout }),
+	},
+	{
+		flag: "write-timeout", env: "SERVIZIO_WRITE_TIMEOUT", usage: "time allowed to write a response",
+		set: durationSetting(func(cfg *Config) *Duration { return &cfg.HTTP.WriteTimeout }),
+	},
+	{
+		flag: "idle-timeout", env: "SERVIZIO_IDLE_TIMEOUT", usage: "time a keep-alive connection is kept open between requests",
+		set: durationSetting(func(cfg *Config) *Duration { return &cfg.HTTP.IdleTimeout }),
+	},
+	{
+		flag: "shutdown-timeout", env: "SERVIZIO_SHUTDOWN_TIMEOUT", usage: "time in-flight requests get to finish on SIGTERM",
+		set: durationSetting(func(cfg *Config) *Duration { return &cfg.HTTP.ShutdownTimeout }),
+	},
+}
+
+// flagValue collects the value of a flag, Load applies it after the file
+// and env vars so that only flags given on the command line override them.
+type flagValue struct {
+	value   string
+	boolean bool
+}
+
+func (f *flagValue) String() string     { return f.value }
+func (f *flagValue) Set(v string) error { f.value = v; return nil }
+func (f *flagValue) IsBoolFlag() bool   { return f.boolean }
+
+// Load builds the config from, in increasing precedence, the defaults, the
+// JSON file named by -config or SERVIZIO_CONFIG, env vars and flags.
+// lookupEnv is usually os.LookupEnv.
+func Load(args []string, lookupEnv func(string) (string, bool)) (Config, error) {
+	fs := flag.NewFlagSet("servizio", flag.ContinueOnError)
+	configFile := fs.String("config", "", "JSON config file, also read from SERVIZIO_CONFIG")
+	values := make([]*flagValue, len(settings))
+	for i, s := range settings {
+		values[i] = &flagValue{boolean: s.boolean}
+		fs.Var(values[i], s.flag, fmt.Sprintf("%v (env %v)", s.usage, s.env))
+	}
+	if err := fs.Parse(args); err != nil {
+		return Config{}, err
+	}
+
+	cfg := Default()
+
+	if *configFile == "" {
+		*configFile, _ = lookupEnv("SERVIZIO_CONFIG")
+	}
+	if *configFile != "" {
+		raw, err := ioutil.ReadFile(*configFile)
+		if err != nil {
+			return Config{}, err
+		}
+		if err := json.Unmarshal(raw, &cfg); err != nil {
+			return Config{}, fmt.Errorf("config file %v: %w", *configFile, err)
+		}
+	}
+
+	for _, s := range settings {
+		if value, ok := lookupEnv(s.env); ok {
+			if err := s.set(&cfg, value); err != nil {
+				return Config{}, fmt.Errorf("env %v: %w", s.env, err)
+			}
+		}
+	}
+
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	for i, s := range settings {
+		if !set[s.flag] {
+			continue
+		}
+		if err := s.set(&cfg, values[i].value); err != nil {
+			return Config{}, fmt.Errorf("flag -%v: %w", s.flag, err)
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+
+	return cfg, nil
+}
+
+func (c Config) Validate() error {
+	if strings.TrimSpace(c.ProjectID) == "" {
+		return errors.New("project id cannot be empty")
+	}
+	if c.Port < 1 || c.Port > 65535 {
+		return fmt.Errorf("invalid port %v", c.Port)
+	}
+	if c.MetricsPort < 0 || c.MetricsPort > 65535 || c.MetricsPort == c.Port {
+		return fmt.Errorf("invalid metrics port %v", c.MetricsPort)
+	}
+	if _, err := logging.ParseLevel(c.LogLevel); err != nil {
+		return err
+	}
+	if c.MaxImageBytes <= 0 {
+		return fmt.Errorf("invalid max image size %v", c.MaxImageBytes)
+	}
+	switch c.Tracing.Exporter {
+	case "none", "stdout", "otlp":
+	default:
+		return fmt.Errorf("unknown trace exporter %q", c.Tracing.Exporter)
+	}
+	timeouts := map[string]Duration{
+		"read header": c.HTTP.ReadHeaderTimeout,
+		"read":        c.HTTP.ReadTimeout,
+		"write":       c.HTTP.WriteTimeout,
+		"idle":        c.HTTP.IdleTimeout,
+		"shutdown":    c.HTTP.ShutdownTimeout,
+	}
+	for name, timeout := range timeouts {
+		if timeout < 0 {
+			return fmt.Errorf("%v timeout cannot be negative", name)
+		}
+	}
+	if c.Memory {
+		return nil
+	}
+	if strings.TrimSpace(c.BucketName) == "" {
+		return errors.New("bucket name cannot be empty")
+	}
+	if c.Collections.Businesses == "" || c.Collections.Clients == "" || c.Collections.Appointments == "" {
+		return errors.New("collection names cannot be empty")
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/tj/assert"
+)
+
+func envFrom(env map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	cfg, err := Load(nil, envFrom(nil))
+	assert.NoError(t, err)
+	assert.Equal(t, Default(), cfg)
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "staging.json")
+	err := ioutil.WriteFile(file, []byte(`{
+		"projectId": "servizio-staging",
+		"bucketName": "servizio-staging.appspot.com",
+		"port": 4000,
+		"collections": {"businesses": "staging-businesses"}
+	}`), 0600)
+	assert.NoError(t, err)
+
+	env := envFrom(map[string]string{
+		"SERVIZIO_CONFIG":         file,
+		"SERVIZIO_PORT":           "5000",
+		"SERVIZIO_AUTH_PROVIDERS": "password, anonymous",
+		"SERVIZIO_MEMORY":         "true",
+	})
+	cfg, err := Load([]string{"-port", "6000", "-require-bearer"}, env)
+	assert.NoError(t, err)
+
+	// the file overrides the defaults
+	assert.Equal(t, "servizio-staging", cfg.ProjectID)
+	assert.Equal(t, "servizio-staging.appspot.com", cfg.BucketName)
+	assert.Equal(t, "staging-businesses", cfg.Collections.Businesses)
+	// fields left out of the file keep their default
+	assert.Equal(t, "clients", cfg.Collections.Clients)
+	// env vars override the file, flags override env vars
+	assert.Equal(t, []string{"password", "anonymous"}, cfg.Auth.Providers)
+	assert.True(t, cfg.Memory)
+	assert.Equal(t, 6000, cfg.Port)
+	assert.True(t, cfg.Auth.RequireBearer)
+	assert.True(t, cfg.Auth.RequireEmailVerified)
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	tests := map[string]struct {
+		args []string
+		env  map[string]string
+	}{
+		"unknown flag":       {args: []string{"-colour", "blue"}},
+		"port not a number":  {env: map[string]string{"SERVIZIO_PORT": "http"}},
+		"port out of range":  {args: []string{"-port", "70000"}},
+		"metrics on the api": {args: []string{"-port", "9090", "-metrics-port", "9090"}},
+		"bad bool":           {env: map[string]string{"SERVIZIO_LOCAL": "sometimes"}},
+		"empty project":      {args: []string{"-project-id", ""}},
+		"missing file":       {args: []string{"-config", "does-not-exist.json"}},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := Load(tt.args, envFrom(tt.env))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestLoad_Timeouts(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.json")
+	err := ioutil.WriteFile(file, []byte(`{"http": {"writeTimeout": "1m", "idleTimeout": "0s"}}`), 0600)
+	assert.NoError(t, err)
+
+	env := envFrom(map[string]string{"SERVIZIO_SHUTDOWN_TIMEOUT": "25s"})
+	cfg, err := Load([]string{"-config", file, "-read-timeout", "2s"}, env)
+	assert.NoError(t, err)
+
+	assert.Equal(t, Duration(2*time.Second), cfg.HTTP.ReadTimeout)
+	assert.Equal(t, Duration(time.Minute), cfg.HTTP.WriteTimeout)
+	assert.Equal(t, Duration(0), cfg.HTTP.IdleTimeout)
+	assert.Equal(t, Duration(25*time.Second), cfg.HTTP.ShutdownTimeout)
+	assert.Equal(t, Default().HTTP.ReadHeaderTimeout, cfg.HTTP.ReadHeaderTimeout)
+
+	_, err = Load([]string{"-write-timeout", "-1s"}, envFrom(nil))
+	assert.Error(t, err)
+	_, err = Load([]string{"-idle-timeout", "forever"}, envFrom(nil))
+	assert.Error(t, err)
+}
@@ -0,0 +1,159 @@
+package images
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/draw"
+)
+
+const (
+	markerSOI  = 0xd8
+	markerSOS  = 0xda
+	markerAPP1 = 0xe1
+
+	tagOrientation = 0x0112
+)
+
+var exifHeader = []byte("Exif\x00\x00")
+
+// jpegSegment is a marker segment of a JPEG before the image data.
+type jpegSegment struct {
+	marker byte
+	// start and end are the offsets of the whole segment in the file,
+	// marker included
+	start, end int
+	payload    []byte
+}
+
+// jpegSegments returns the segments of raw up to the start of scan, and
+// false if raw is not a well formed JPEG.
+func jpegSegments(raw []byte) ([]jpegSegment, bool) {
+	if len(raw) < 2 || raw[0] != 0xff || raw[1] != markerSOI {
+		return nil, false
+	}
+
+	var segments []jpegSegment
+	for i := 2; i+4 <= len(raw); {
+		if raw[i] != 0xff {
+			return nil, false
+		}
+		marker := raw[i+1]
+		if marker == 0xff {
+			// fill byte
+			i++
+			continue
+		}
+		length := int(binary.BigEndian.Uint16(raw[i+2:]))
+		if length < 2 || i+2+length > len(raw) {
+			return nil, false
+		}
+		segments = append(segments, jpegSegment{
+			marker:  marker,
+			start:   i,
+			end:     i + 2 + length,
+			payload: raw[i+4 : i+2+length],
+		})
+		if marker == markerSOS {
+			return segments, true
+		}
+		i += 2 + length
+	}
+
+	return nil, false
+}
+
+// exifOrientation returns the EXIF orientation of a JPEG, from 1 to 8, and
+// 1 if it has none.
+func exifOrientation(raw []byte) int {
+	segments, ok := jpegSegments(raw)
+	if !ok {
+		return 1
+	}
+	for _, segment := range segments {
+		if segment.marker != markerAPP1 || !bytes.HasPrefix(segment.payload, exifHeader) {
+			continue
+		}
+		if o := tiffOrientation(segment.payload[len(exifHeader):]); o >= 1 && o <= 8 {
+			return o
+		}
+	}
+	return 1
+}
+
+// tiffOrientation reads the orientation tag of the first IFD of the TIFF
+// structure held by an EXIF segment.
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 0
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+
+	offset := int(order.Uint32(tiff[4:]))
+	if offset < 8 || offset+2 > len(tiff) {
+		return 0
+	}
+	count := int(order.Uint16(tiff[offset:]))
+	for i := 0; i < count; i++ {
+		entry := offset + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 0
+		}
+		if order.Uint16(tiff[entry:]) == tagOrientation {
+			return int(order.Uint16(tiff[entry+8:]))
+		}
+	}
+	return 0
+}
+
+// orient turns img the way the EXIF orientation says it should be shown.
+func orient(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+
+	b := img.Bounds()
+	src := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(src, src.Bounds(), img, b.Min, draw.Src)
+	w, h := b.Dx(), b.Dy()
+
+	dstW, dstH := w, h
+	if orientation >= 5 {
+		// the orientations from 5 on turn the image by 90 degrees
+		dstW, dstH = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+
+	for y := 0; y < dstH; y++ {
+		for x := 0; x < dstW; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2: // mirrored
+				sx, sy = w-1-x, y
+			case 3: // rotated 180
+				sx, sy = w-1-x, h-1-y
+			case 4: // mirrored vertically
+				sx, sy = x, h-1-y
+			case 5: // transposed
+				sx, sy = y, x
+			case 6: // rotated 90 clockwise
+				sx, sy = y, h-1-x
+			case 7: // transversed
+				sx, sy = w-1-y, h-1-x
+			case 8: // rotated 90 counter clockwise
+				sx, sy = w-1-y, x
+			}
+			copy(dst.Pix[dst.PixOffset(x, y):dst.PixOffset(x, y)+4], src.Pix[src.PixOffset(sx, sy):src.PixOffset(sx, sy)+4])
+		}
+	}
+
+	return dst
+}
@@ -2,25 +2,271 @@ package images
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"strconv"
+	"strings"
 
 	"cloud.google.com/go/storage"
+	"github.com/devduck123/servizio-be/internal/logging"
+	"github.com/devduck123/servizio-be/internal/tracing"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
-var projectID = "servizio-be"
+var (
+	ErrImageNotFound = errors.New("image not found")
+	ErrInvalidOrder  = errors.New("order must list every image exactly once")
+)
+
+// Store is implemented by ImageManager, backed by Cloud Storage, and by
+// MemoryStore.
+type Store interface {
+	// UploadImage stores the original and the variants of upload under
+	// keys starting with id.
+	UploadImage(ctx context.Context, id string, upload Upload) (Image, error)
+	GetImage(ctx context.Context, objectPath string) ([]byte, error)
+	// OpenImage starts reading the image stored at key, the caller must
+	// close it. It returns ErrImageNotFound if there is no such image.
+	OpenImage(ctx context.Context, key string) (*Object, error)
+	// DeleteImage removes the image stored at key along with its variants,
+	// returning ErrImageNotFound if there is no such image.
+	DeleteImage(ctx context.Context, key string) error
+}
+
+var (
+	_ Store = ImageManager{}
+	_ Store = (*MemoryStore)(nil)
+)
 
 type Image struct {
 	SignedURL string
 	Key       string
+	// Variants holds the keys of the resized copies by size
+	Variants map[int]string
+}
+
+// Ref returns what businesses and clients keep of the image.
+func (image Image) Ref() Ref {
+	ref := Ref{
+		Key:      image.Key,
+		Variants: make(map[string]string, len(image.Variants)),
+	}
+	for size, key := range image.Variants {
+		ref.Variants[strconv.Itoa(size)] = key
+	}
+	return ref
+}
+
+// Object is a stored image being read.
+type Object struct {
+	io.ReadCloser
+	// ContentType is empty for images stored without one
+	ContentType string
+	Size        int64
+	// ETag changes whenever the content does, it is not quoted
+	ETag string
+}
+
+// Ref is an uploaded image as kept on businesses and clients, the key of
+// the original and the keys of its variants by size. Keys stay valid
+// wherever the images are served from, the URLs are only added to JSON.
+type Ref struct {
+	Key      string            `json:"key" firestore:"key"`
+	Variants map[string]string `json:"-" firestore:"variants,omitempty"`
+}
+
+// refJSON is how a Ref is sent to clients, with the URLs to fetch it at.
+type refJSON struct {
+	Key      string            `json:"key"`
+	URL      string            `json:"url"`
+	Variants map[string]string `json:"variants,omitempty"`
+}
+
+func (ref Ref) MarshalJSON() ([]byte, error) {
+	out := refJSON{
+		Key: ref.Key,
+		URL: URL(ref.Key),
+	}
+	if len(ref.Variants) > 0 {
+		out.Variants = make(map[string]string, len(ref.Variants))
+		for size := range ref.Variants {
+			out.Variants[size] = URL(ref.Key) + "?size=" + size
+		}
+	}
+	return json.Marshal(out)
+}
+
+// URLPath is where the server serves images, followed by their key.
+const URLPath = "/images/"
+
+// URL returns the URL, relative to the server, an image is served at.
+func URL(key string) string {
+	return URLPath + key
+}
+
+// RefsFromFirestore turns the images of a business or client document into
+// refs. Documents written before keys were kept hold the URLs of the
+// images instead, such as "servizio-be.appspot.com/<key>", or objects with
+// a url in place of the key, which are turned into their keys.
+func RefsFromFirestore(values []interface{}) ([]Ref, error) {
+	if values == nil {
+		return nil, nil
+	}
+	refs := make([]Ref, 0, len(values))
+	for _, value := range values {
+		switch value := value.(type) {
+		case string:
+			refs = append(refs, Ref{Key: keyFromURL(value)})
+		case map[string]interface{}:
+			ref, err := refFromMap(value)
+			if err != nil {
+				return nil, err
+			}
+			refs = append(refs, ref)
+		default:
+			return nil, fmt.Errorf("images: cannot read %T as an image", value)
+		}
+	}
+	return refs, nil
+}
+
+func refFromMap(value map[string]interface{}) (Ref, error) {
+	key, hasKey := value["key"].(string)
+	url, hasURL := value["url"].(string)
+	if !hasKey && !hasURL {
+		return Ref{}, fmt.Errorf("images: image without a key: %v", value)
+	}
+
+	ref := Ref{Key: key}
+	if !hasKey {
+		ref.Key = keyFromURL(url)
+	}
+	if variants, ok := value["variants"].(map[string]interface{}); ok {
+		ref.Variants = make(map[string]string, len(variants))
+		for size, variant := range variants {
+			variantKey, ok := variant.(string)
+			if !ok {
+				return Ref{}, fmt.Errorf("images: cannot read %T as a variant", variant)
+			}
+			if !hasKey {
+				variantKey = keyFromURL(variantKey)
+			}
+			ref.Variants[size] = variantKey
+		}
+	}
+	return ref, nil
+}
+
+// keyFromURL returns the key of an image from the URL it was kept as, the
+// part after the bucket name.
+func keyFromURL(url string) string {
+	if i := strings.Index(url, "://"); i >= 0 {
+		url = url[i+len("://"):]
+	}
+	url = strings.TrimPrefix(url, "storage.googleapis.com/")
+	if i := strings.Index(url, "/"); i >= 0 {
+		return url[i+1:]
+	}
+	return url
+}
+
+// CopyRefs returns a copy of refs sharing nothing with it.
+func CopyRefs(refs []Ref) []Ref {
+	if refs == nil {
+		return nil
+	}
+	copied := make([]Ref, len(refs))
+	for n, ref := range refs {
+		copied[n] = Ref{Key: ref.Key}
+		if ref.Variants != nil {
+			copied[n].Variants = make(map[string]string, len(ref.Variants))
+			for size, key := range ref.Variants {
+				copied[n].Variants[size] = key
+			}
+		}
+	}
+	return copied
+}
+
+// RemoveRef returns refs without the image with the given key, and false
+// if refs has no such image.
+func RemoveRef(refs []Ref, key string) ([]Ref, bool) {
+	for n, ref := range refs {
+		if ref.Key == key {
+			removed := make([]Ref, 0, len(refs)-1)
+			removed = append(removed, refs[:n]...)
+			return append(removed, refs[n+1:]...), true
+		}
+	}
+	return refs, false
+}
+
+// OrderRefs returns refs in the order of keys, returning ErrInvalidOrder
+// unless keys lists the key of every image once.
+func OrderRefs(refs []Ref, keys []string) ([]Ref, error) {
+	if len(keys) != len(refs) {
+		return nil, ErrInvalidOrder
+	}
+	byKey := make(map[string]Ref, len(refs))
+	for _, ref := range refs {
+		byKey[ref.Key] = ref
+	}
+
+	ordered := make([]Ref, 0, len(refs))
+	for _, key := range keys {
+		ref, ok := byKey[key]
+		if !ok {
+			return nil, ErrInvalidOrder
+		}
+		// also rejects keys listed twice
+		delete(byKey, key)
+		ordered = append(ordered, ref)
+	}
+	return ordered, nil
+}
+
+// objects returns the objects to write for upload stored at key, the
+// original first.
+func objects(key string, upload Upload) (keys []string, contentTypes []string, raws [][]byte) {
+	keys = append(keys, key)
+	contentTypes = append(contentTypes, upload.ContentType)
+	raws = append(raws, upload.Raw)
+	for _, variant := range upload.Variants {
+		keys = append(keys, VariantKey(key, variant.Size))
+		contentTypes = append(contentTypes, VariantContentType)
+		raws = append(raws, variant.Raw)
+	}
+	return keys, contentTypes, raws
+}
+
+// newImage returns the image stored at key with the variants of upload.
+func newImage(key string, upload Upload) Image {
+	image := Image{
+		Key:      key,
+		Variants: make(map[int]string, len(upload.Variants)),
+	}
+	for _, variant := range upload.Variants {
+		image.Variants[variant.Size] = VariantKey(key, variant.Size)
+	}
+	return image
 }
 
 type ImageManager struct {
 	BucketName string
 }
 
-func (i ImageManager) UploadImage(ctx context.Context, id string, raw []byte) (Image, error) {
+func (i ImageManager) UploadImage(ctx context.Context, id string, upload Upload) (image Image, err error) {
+	ctx, span := startSpan(ctx, "images.UploadImage", i.BucketName)
+	defer func() {
+		span.SetAttributes(attribute.String("servizio.image.key", image.Key), attribute.Int("servizio.image.size", len(upload.Raw)))
+		tracing.End(span, err)
+	}()
+
 	client, err := storage.NewClient(ctx)
 	if err != nil {
 		return Image{}, err
@@ -30,58 +276,52 @@ func (i ImageManager) UploadImage(ctx context.Context, id string, raw []byte) (I
 	objectPath := fmt.Sprintf("%s/%s", id, uuid.New().String())
 	bucketName := i.BucketName
 
-	// bucketIterator := client.Buckets(ctx, projectID)
-	// for {
-	// 	attrs, err := bucketIterator.Next()
-	// 	if err == iterator.Done {
-	// 		break
-	// 	}
-	// 	if err != nil {
-	// 		return Image{}, err
-	// 	}
-	// 	fmt.Println("bucketName:", attrs.Name)
-	// }
-
-	fmt.Println("bucketName:", bucketName)
-	fmt.Println("invoking Bucket")
+	logger := logging.FromContext(ctx).With("bucket", bucketName, "key", objectPath)
 	bucket := client.Bucket(bucketName)
 
-	fmt.Println("invoking bucket.Object")
-	object := bucket.Object(objectPath)
-	fmt.Println("invoking object.NewWriter")
-	w := object.NewWriter(ctx)
-	fmt.Println("invoking w.Write")
-	_, err = w.Write(raw)
-
-	if err != nil {
-		return Image{}, err
-	}
-	fmt.Println("invoking w.Close")
-	if err := w.Close(); err != nil {
-		return Image{}, fmt.Errorf("Writer.Close: %v", err)
+	keys, contentTypes, raws := objects(objectPath, upload)
+	for n, key := range keys {
+		logger.Debug("uploading image", "object", key, "size", len(raws[n]))
+		w := bucket.Object(key).NewWriter(ctx)
+		w.ContentType = contentTypes[n]
+		_, err = w.Write(raws[n])
+		if err != nil {
+			w.Close()
+			return Image{}, err
+		}
+		if err := w.Close(); err != nil {
+			return Image{}, fmt.Errorf("Writer.Close: %v", err)
+		}
 	}
 
-	return Image{
-		Key: objectPath,
-	}, nil
+	return newImage(objectPath, upload), nil
 }
 
-func (i ImageManager) GetImage(ctx context.Context, objectPath string) ([]byte, error) {
+func (i ImageManager) GetImage(ctx context.Context, objectPath string) (raw []byte, err error) {
+	ctx, span := startSpan(ctx, "images.GetImage", i.BucketName)
+	span.SetAttributes(attribute.String("servizio.image.key", objectPath))
+	defer func() {
+		tracing.End(span, err)
+	}()
+
 	client, err := storage.NewClient(ctx)
 	if err != nil {
 		return nil, err
 	}
 	defer client.Close()
 	bucketName := i.BucketName
-	fmt.Println("objectPath:", objectPath)
+	logging.FromContext(ctx).Debug("reading image", "bucket", bucketName, "key", objectPath)
 	bucket := client.Bucket(bucketName)
 	object := bucket.Object(objectPath)
 
 	reader, err := object.NewReader(ctx)
 	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return nil, ErrImageNotFound
+		}
 		return nil, err
 	}
-	raw, err := ioutil.ReadAll(reader)
+	raw, err = ioutil.ReadAll(reader)
 	if err != nil {
 		return nil, err
 	}
@@ -93,40 +333,89 @@ func (i ImageManager) GetImage(ctx context.Context, objectPath string) ([]byte,
 	return raw, nil
 }
 
-// TODO: review this code to check if useful...
-// func (i ImageManager) createBucket(ctx context.Context, bucketName string) (string, error) {
-// 	bucket := client.Bucket(bucketName)
-// 	err := bucket.Create(ctx, projectID, nil)
-// 	if err != nil {
-// 		return "", err
-// 	}
-// 	return fmt.Sprintf("created bucket: %s", bucketName), nil
-// }
-
-// func (i ImageManager) deleteBucket(ctx context.Context, bucketName string) (string, error) {
-// 	bucketToDelete := client.Bucket(bucketName)
-// 	err := bucketToDelete.Delete(ctx)
-// 	if err != nil {
-// 		return "", err
-// 	}
-// 	return fmt.Sprintf("successfully deleted bucket %s", bucketName), nil
-// }
-
-// func (i ImageManager) GetSignedURL(ctx context.Context) (Image, error) {
-// 	key := uuid.New().String()
-
-// 	url, err := client.Bucket(i.BucketName).SignedURL(key, &storage.SignedURLOptions{
-// 		Method:         "PUT",
-// 		Expires:        time.Now().Add(15 * time.Minute),
-// 		Scheme:         storage.SigningSchemeV4,
-// 		GoogleAccessID: "xxx@developer.gserviceaccount.com",
-// 	})
-// 	if err != nil {
-// 		return Image{}, err
-// 	}
-
-// 	return Image{
-// 		SignedURL: url,
-// 		Key:       key,
-// 	}, nil
-// }
+func (i ImageManager) OpenImage(ctx context.Context, key string) (object *Object, err error) {
+	ctx, span := startSpan(ctx, "images.OpenImage", i.BucketName)
+	span.SetAttributes(attribute.String("servizio.image.key", key))
+	defer func() {
+		tracing.End(span, err)
+	}()
+
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	logging.FromContext(ctx).Debug("opening image", "bucket", i.BucketName, "key", key)
+
+	reader, err := client.Bucket(i.BucketName).Object(key).NewReader(ctx)
+	if err != nil {
+		client.Close()
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return nil, ErrImageNotFound
+		}
+		return nil, err
+	}
+
+	return &Object{
+		ReadCloser:  storageReader{Reader: reader, client: client},
+		ContentType: reader.Attrs.ContentType,
+		Size:        reader.Attrs.Size,
+		// the generation changes every time an object is written
+		ETag: strconv.FormatInt(reader.Attrs.Generation, 10),
+	}, nil
+}
+
+// storageReader closes the client along with the reader, which needs it
+// until it is done.
+type storageReader struct {
+	*storage.Reader
+	client *storage.Client
+}
+
+func (r storageReader) Close() error {
+	err := r.Reader.Close()
+	if closeErr := r.client.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func (i ImageManager) DeleteImage(ctx context.Context, key string) (err error) {
+	ctx, span := startSpan(ctx, "images.DeleteImage", i.BucketName)
+	span.SetAttributes(attribute.String("servizio.image.key", key))
+	defer func() {
+		tracing.End(span, err)
+	}()
+
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	bucket := client.Bucket(i.BucketName)
+	logging.FromContext(ctx).Debug("deleting image", "bucket", i.BucketName, "key", key)
+
+	// images uploaded before variants existed have none, so missing
+	// variants are not an error
+	for _, size := range VariantSizes {
+		err := bucket.Object(VariantKey(key, size)).Delete(ctx)
+		if err != nil && !errors.Is(err, storage.ErrObjectNotExist) {
+			return err
+		}
+	}
+	if err := bucket.Object(key).Delete(ctx); err != nil {
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return ErrImageNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+// startSpan starts a span for a call to Cloud Storage.
+func startSpan(ctx context.Context, name, bucketName string) (context.Context, trace.Span) {
+	return tracing.Tracer().Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("servizio.image.bucket", bucketName)),
+	)
+}
@@ -2,7 +2,9 @@ package images
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"os"
 	"testing"
@@ -10,8 +12,6 @@ import (
 	"github.com/tj/assert"
 )
 
-// var projectID = "servizio-be"
-
 func TestMain(m *testing.M) {
 	if err := os.Setenv("FIRESTORE_EMULATOR_HOST", "localhost:8080"); err != nil {
 		log.Fatal("failed to set FIRESTORE_EMULATOR_HOST environment variable", err)
@@ -34,15 +34,74 @@ func TestUploadImage(t *testing.T) {
 		BucketName: "servizio-be.appspot.com",
 	}
 
-	raw := []byte("hello")
-	image, err := im.UploadImage(ctx, "foo", raw)
+	upload, err := Prepare(withGPS(readSample(t, "sample.jpeg")))
+	assert.NoError(t, err)
+	image, err := im.UploadImage(ctx, "foo", upload)
 	assert.NoError(t, err)
 
 	fmt.Println("image key:", image.Key)
 
 	gotRaw, err := im.GetImage(ctx, image.Key)
 	assert.NoError(t, err)
-	assert.Equal(t, raw, gotRaw)
+	assert.Equal(t, upload.Raw, gotRaw)
+	assert.False(t, hasMetadata(t, gotRaw))
+
+	object, err := im.OpenImage(ctx, image.Key)
+	assert.NoError(t, err)
+	defer object.Close()
+	assert.Equal(t, ContentTypeJPEG, object.ContentType)
+	assert.Equal(t, int64(len(upload.Raw)), object.Size)
+	assert.NotEmpty(t, object.ETag)
+	gotRaw, err = ioutil.ReadAll(object)
+	assert.NoError(t, err)
+	assert.Equal(t, upload.Raw, gotRaw)
+
+	for _, variant := range upload.Variants {
+		gotRaw, err := im.GetImage(ctx, image.Variants[variant.Size])
+		assert.NoError(t, err)
+		assert.Equal(t, variant.Raw, gotRaw)
+	}
+}
+
+func TestRef_MarshalJSON(t *testing.T) {
+	ref := Image{
+		Key:      "foo/bar",
+		Variants: map[int]string{128: "foo/bar_128"},
+	}.Ref()
+	assert.Equal(t, Ref{Key: "foo/bar", Variants: map[string]string{"128": "foo/bar_128"}}, ref)
+
+	raw, err := json.Marshal(ref)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"key": "foo/bar", "url": "/images/foo/bar", "variants": {"128": "/images/foo/bar?size=128"}}`, string(raw))
+
+	raw, err = json.Marshal(Ref{Key: "foo/bar"})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"key": "foo/bar", "url": "/images/foo/bar"}`, string(raw))
+}
+
+func TestRefsFromFirestore(t *testing.T) {
+	refs, err := RefsFromFirestore([]interface{}{
+		"servizio-be.appspot.com/foo/bar",
+		"https://storage.googleapis.com/servizio-be.appspot.com/foo/baz",
+		map[string]interface{}{"key": "foo/qux", "variants": map[string]interface{}{"128": "foo/qux_128"}},
+		map[string]interface{}{"url": "servizio-be.appspot.com/foo/quux", "variants": map[string]interface{}{"128": "servizio-be.appspot.com/foo/quux_128"}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []Ref{
+		{Key: "foo/bar"},
+		{Key: "foo/baz"},
+		{Key: "foo/qux", Variants: map[string]string{"128": "foo/qux_128"}},
+		{Key: "foo/quux", Variants: map[string]string{"128": "foo/quux_128"}},
+	}, refs)
+
+	refs, err = RefsFromFirestore(nil)
+	assert.NoError(t, err)
+	assert.Nil(t, refs)
+
+	_, err = RefsFromFirestore([]interface{}{int64(1)})
+	assert.Error(t, err)
+	_, err = RefsFromFirestore([]interface{}{map[string]interface{}{"name": "foo"}})
+	assert.Error(t, err)
 }
 
 func TestGetImage_Exists(t *testing.T) {
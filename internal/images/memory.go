@@ -0,0 +1,90 @@
+package images
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// MemoryStore keeps images in memory, for tests and for running the server
+// without Cloud Storage.
+type MemoryStore struct {
+	mu      sync.Mutex
+	objects map[string]memoryObject
+}
+
+type memoryObject struct {
+	contentType string
+	raw         []byte
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		objects: make(map[string]memoryObject),
+	}
+}
+
+func (m *MemoryStore) UploadImage(ctx context.Context, id string, upload Upload) (Image, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	objectPath := fmt.Sprintf("%s/%s", id, uuid.New().String())
+	keys, contentTypes, raws := objects(objectPath, upload)
+	for n, key := range keys {
+		m.objects[key] = memoryObject{
+			contentType: contentTypes[n],
+			raw:         append([]byte(nil), raws[n]...),
+		}
+	}
+
+	return newImage(objectPath, upload), nil
+}
+
+func (m *MemoryStore) GetImage(ctx context.Context, objectPath string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	object, ok := m.objects[objectPath]
+	if !ok {
+		return nil, ErrImageNotFound
+	}
+	return append([]byte(nil), object.raw...), nil
+}
+
+func (m *MemoryStore) OpenImage(ctx context.Context, key string) (*Object, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	object, ok := m.objects[key]
+	if !ok {
+		return nil, ErrImageNotFound
+	}
+	// stored objects are never modified, so readers can share them
+	sum := sha256.Sum256(object.raw)
+	return &Object{
+		ReadCloser:  ioutil.NopCloser(bytes.NewReader(object.raw)),
+		ContentType: object.contentType,
+		Size:        int64(len(object.raw)),
+		ETag:        hex.EncodeToString(sum[:16]),
+	}, nil
+}
+
+func (m *MemoryStore) DeleteImage(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.objects[key]; !ok {
+		return ErrImageNotFound
+	}
+	delete(m.objects, key)
+	for _, size := range VariantSizes {
+		delete(m.objects, VariantKey(key, size))
+	}
+	return nil
+}
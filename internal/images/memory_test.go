@@ -0,0 +1,80 @@
+package images
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/tj/assert"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	upload := Upload{
+		ContentType: ContentTypePNG,
+		Raw:         []byte("original"),
+		Variants:    []Variant{{Size: 128, Raw: []byte("small")}},
+	}
+	image, err := store.UploadImage(ctx, "foo", upload)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(image.Key, "foo/"))
+	assert.Equal(t, map[int]string{128: image.Key + "_128"}, image.Variants)
+
+	gotRaw, err := store.GetImage(ctx, image.Key)
+	assert.NoError(t, err)
+	assert.Equal(t, upload.Raw, gotRaw)
+
+	gotRaw, err = store.GetImage(ctx, image.Variants[128])
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("small"), gotRaw)
+
+	_, err = store.GetImage(ctx, "foo/notexists")
+	assert.Equal(t, ErrImageNotFound, err)
+}
+
+func TestMemoryStore_DeleteImage(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	image, err := store.UploadImage(ctx, "foo", Upload{
+		ContentType: ContentTypePNG,
+		Raw:         []byte("original"),
+		Variants:    []Variant{{Size: 128, Raw: []byte("small")}},
+	})
+	assert.NoError(t, err)
+
+	assert.NoError(t, store.DeleteImage(ctx, image.Key))
+	_, err = store.GetImage(ctx, image.Key)
+	assert.Equal(t, ErrImageNotFound, err)
+	_, err = store.GetImage(ctx, image.Variants[128])
+	assert.Equal(t, ErrImageNotFound, err)
+
+	assert.Equal(t, ErrImageNotFound, store.DeleteImage(ctx, image.Key))
+}
+
+func TestOrderRefs(t *testing.T) {
+	refs := []Ref{{Key: "a"}, {Key: "b"}, {Key: "c"}}
+
+	ordered, err := OrderRefs(refs, []string{"b", "c", "a"})
+	assert.NoError(t, err)
+	assert.Equal(t, []Ref{{Key: "b"}, {Key: "c"}, {Key: "a"}}, ordered)
+
+	for _, keys := range [][]string{
+		{"a", "b"},
+		{"a", "b", "b"},
+		{"a", "b", "d"},
+		{"a", "b", "c", "d"},
+	} {
+		_, err := OrderRefs(refs, keys)
+		assert.Equal(t, ErrInvalidOrder, err, "%v", keys)
+	}
+
+	remaining, ok := RemoveRef(refs, "b")
+	assert.True(t, ok)
+	assert.Equal(t, []Ref{{Key: "a"}, {Key: "c"}}, remaining)
+	assert.Len(t, refs, 3)
+	_, ok = RemoveRef(refs, "d")
+	assert.False(t, ok)
+}
@@ -0,0 +1,206 @@
+package images
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+const (
+	markerEOI   = 0xd9
+	markerRST0  = 0xd0
+	markerRST7  = 0xd7
+	markerAPP0  = 0xe0
+	markerAPP2  = 0xe2
+	markerAPP14 = 0xee
+	markerAPP15 = 0xef
+	markerCOM   = 0xfe
+
+	// the flags of the VP8X chunk of a WebP telling it has metadata
+	webpFlagEXIF = 0x08
+	webpFlagXMP  = 0x04
+)
+
+var (
+	pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+	// pngMetadataChunks are the PNG chunks holding EXIF, text and the time
+	// of the last change
+	pngMetadataChunks = map[string]bool{
+		"eXIf": true,
+		"tEXt": true,
+		"zTXt": true,
+		"iTXt": true,
+		"tIME": true,
+	}
+)
+
+// stripMetadata returns a copy of raw, an image of the given content type,
+// without the metadata it carries, such as EXIF with the location a photo
+// was taken at, XMP and comments. Only what is needed to show the image
+// the same is kept.
+func stripMetadata(contentType string, raw []byte) ([]byte, error) {
+	var stripped []byte
+	var ok bool
+	switch contentType {
+	case ContentTypeJPEG:
+		stripped, ok = stripJPEG(raw)
+	case ContentTypePNG:
+		stripped, ok = stripPNG(raw)
+	case ContentTypeWebP:
+		stripped, ok = stripWebP(raw)
+	default:
+		return nil, ErrUnsupportedType
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: malformed %v", ErrInvalidImage, contentType)
+	}
+	return stripped, nil
+}
+
+// stripJPEG drops the application segments and comments of a JPEG but for
+// JFIF, the ICC color profile and the Adobe segment, which change how the
+// image is decoded. Everything after the end of the image is dropped too,
+// phones append secondary images there with their own EXIF.
+func stripJPEG(raw []byte) ([]byte, bool) {
+	if len(raw) < 2 || raw[0] != 0xff || raw[1] != markerSOI {
+		return nil, false
+	}
+
+	stripped := make([]byte, 0, len(raw))
+	stripped = append(stripped, raw[:2]...)
+	for i := 2; i+2 <= len(raw); {
+		if raw[i] != 0xff {
+			return nil, false
+		}
+		marker := raw[i+1]
+		if marker == 0xff {
+			// fill byte
+			i++
+			continue
+		}
+		if marker == markerEOI {
+			return append(stripped, raw[i:i+2]...), true
+		}
+
+		if i+4 > len(raw) {
+			return nil, false
+		}
+		end := i + 2 + int(binary.BigEndian.Uint16(raw[i+2:]))
+		if end < i+4 || end > len(raw) {
+			return nil, false
+		}
+		segment := jpegSegment{marker: marker, start: i, end: end, payload: raw[i+4 : end]}
+		if keepJPEGSegment(segment) {
+			stripped = append(stripped, raw[i:end]...)
+		}
+		i = end
+
+		if marker == markerSOS {
+			// progressive images have several scans, with segments
+			// between them
+			dataEnd := scanEnd(raw, i)
+			stripped = append(stripped, raw[i:dataEnd]...)
+			i = dataEnd
+		}
+	}
+	return nil, false
+}
+
+// scanEnd returns the offset of the marker ending the image data of a scan
+// starting at i. In the data 0xff is followed by a stuffed zero, a restart
+// marker or another 0xff, anything else is a marker.
+func scanEnd(raw []byte, i int) int {
+	for ; i+1 < len(raw); i++ {
+		if raw[i] != 0xff {
+			continue
+		}
+		next := raw[i+1]
+		if next == 0 || next == 0xff || (next >= markerRST0 && next <= markerRST7) {
+			continue
+		}
+		return i
+	}
+	return len(raw)
+}
+
+func keepJPEGSegment(segment jpegSegment) bool {
+	switch {
+	case segment.marker == markerCOM:
+		return false
+	case segment.marker < markerAPP0 || segment.marker > markerAPP15:
+		return true
+	case segment.marker == markerAPP0:
+		return bytes.HasPrefix(segment.payload, []byte("JFIF\x00"))
+	case segment.marker == markerAPP2:
+		return bytes.HasPrefix(segment.payload, []byte("ICC_PROFILE\x00"))
+	case segment.marker == markerAPP14:
+		return bytes.HasPrefix(segment.payload, []byte("Adobe"))
+	}
+	return false
+}
+
+// stripPNG drops the chunks listed in pngMetadataChunks.
+func stripPNG(raw []byte) ([]byte, bool) {
+	if !bytes.HasPrefix(raw, pngSignature) {
+		return nil, false
+	}
+
+	stripped := make([]byte, 0, len(raw))
+	stripped = append(stripped, pngSignature...)
+	for i := len(pngSignature); i < len(raw); {
+		if i+12 > len(raw) {
+			return nil, false
+		}
+		// length, type, data and CRC
+		end := i + 12 + int(binary.BigEndian.Uint32(raw[i:]))
+		if end < i+12 || end > len(raw) {
+			return nil, false
+		}
+		chunkType := string(raw[i+4 : i+8])
+		if !pngMetadataChunks[chunkType] {
+			stripped = append(stripped, raw[i:end]...)
+		}
+		if chunkType == "IEND" {
+			return stripped, true
+		}
+		i = end
+	}
+	return nil, false
+}
+
+// stripWebP drops the EXIF and XMP chunks of a WebP and clears the flags
+// announcing them.
+func stripWebP(raw []byte) ([]byte, bool) {
+	if len(raw) < 12 || string(raw[:4]) != "RIFF" || string(raw[8:12]) != "WEBP" {
+		return nil, false
+	}
+
+	stripped := make([]byte, 12, len(raw))
+	copy(stripped, raw[:12])
+	for i := 12; i < len(raw); {
+		if i+8 > len(raw) {
+			return nil, false
+		}
+		size := int(binary.LittleEndian.Uint32(raw[i+4:]))
+		// chunks are padded to an even size
+		end := i + 8 + size + size%2
+		if end < i+8 || end > len(raw) {
+			return nil, false
+		}
+		switch chunkType := string(raw[i : i+4]); chunkType {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			start := len(stripped)
+			stripped = append(stripped, raw[i:end]...)
+			if size > 0 {
+				stripped[start+8] &^= webpFlagEXIF | webpFlagXMP
+			}
+		default:
+			stripped = append(stripped, raw[i:end]...)
+		}
+		i = end
+	}
+	binary.LittleEndian.PutUint32(stripped[4:], uint32(len(stripped)-8))
+	return stripped, true
+}
@@ -0,0 +1,165 @@
+package images
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image/jpeg"
+	"testing"
+
+	"github.com/tj/assert"
+)
+
+const (
+	tagGPSInfo        = 0x8825
+	tagGPSLatitudeRef = 0x0001
+)
+
+// withGPS inserts an EXIF segment holding a GPS latitude right after the
+// start of a JPEG, along with a comment.
+func withGPS(raw []byte) []byte {
+	var tiff bytes.Buffer
+	tiff.WriteString("MM\x00*")
+	binary.Write(&tiff, binary.BigEndian, uint32(8))
+	// the first IFD points at the GPS IFD following it, at 8+2+12+4
+	binary.Write(&tiff, binary.BigEndian, uint16(1))
+	binary.Write(&tiff, binary.BigEndian, []uint16{tagGPSInfo, 4})
+	binary.Write(&tiff, binary.BigEndian, []uint32{1, 26, 0})
+	binary.Write(&tiff, binary.BigEndian, uint16(1))
+	binary.Write(&tiff, binary.BigEndian, []uint16{tagGPSLatitudeRef, 2})
+	binary.Write(&tiff, binary.BigEndian, uint32(2))
+	tiff.WriteString("N\x00\x00\x00")
+	binary.Write(&tiff, binary.BigEndian, uint32(0))
+
+	raw = withSegment(raw, markerAPP1, append(append([]byte{}, exifHeader...), tiff.Bytes()...))
+	return withSegment(raw, markerCOM, []byte("taken at home"))
+}
+
+// hasMetadata reports whether a JPEG has EXIF, XMP or comment segments.
+func hasMetadata(t *testing.T, raw []byte) bool {
+	t.Helper()
+
+	segments, ok := jpegSegments(raw)
+	assert.True(t, ok)
+	for _, segment := range segments {
+		if segment.marker == markerCOM || segment.marker == markerAPP1 {
+			return true
+		}
+	}
+	return false
+}
+
+func TestPrepare_StripsLocation(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	raw := withGPS(readSample(t, "sample.jpeg"))
+	assert.True(t, hasMetadata(t, raw))
+
+	upload, err := Prepare(raw)
+	assert.NoError(t, err)
+	image, err := store.UploadImage(ctx, "foo", upload)
+	assert.NoError(t, err)
+
+	stored, err := store.GetImage(ctx, image.Key)
+	assert.NoError(t, err)
+	assert.False(t, hasMetadata(t, stored))
+	assert.False(t, bytes.Contains(stored, exifHeader))
+	assert.False(t, bytes.Contains(stored, []byte("taken at home")))
+	// only metadata is dropped, the image data is kept as sent
+	assert.True(t, bytes.HasSuffix(raw, stored[bytes.Index(stored, []byte{0xff, markerSOS}):]))
+
+	for _, key := range image.Variants {
+		stored, err := store.GetImage(ctx, key)
+		assert.NoError(t, err)
+		assert.False(t, hasMetadata(t, stored))
+	}
+}
+
+func TestPrepare_StripsTrailingData(t *testing.T) {
+	raw := readSample(t, "sample.jpeg")
+
+	// phones append secondary images after the end of the main one, each
+	// with EXIF of its own
+	trailing := withGPS([]byte{0xff, markerSOI, 0xff, markerEOI})
+	upload, err := Prepare(append(append([]byte{}, raw...), trailing...))
+	assert.NoError(t, err)
+	assert.False(t, bytes.Contains(upload.Raw, exifHeader))
+	assert.False(t, bytes.Contains(upload.Raw, []byte("taken at home")))
+	assert.Equal(t, raw, upload.Raw)
+}
+
+func TestPrepare_StripsLocation_Oriented(t *testing.T) {
+	raw := withGPS(withEXIF(halfRed(t, 64, 32), 6))
+
+	upload, err := Prepare(raw)
+	assert.NoError(t, err)
+	assert.False(t, hasMetadata(t, upload.Raw))
+
+	// re-encoded turned rather than left to a stripped orientation
+	img, err := jpeg.Decode(bytes.NewReader(upload.Raw))
+	assert.NoError(t, err)
+	assert.Equal(t, 32, img.Bounds().Dx())
+	assert.Equal(t, 64, img.Bounds().Dy())
+}
+
+func TestStripMetadata_PNG(t *testing.T) {
+	raw := readSample(t, "sample.png")
+	assert.True(t, bytes.Contains(raw, []byte("tEXt")))
+
+	// the sample only has text chunks, add EXIF after the header
+	exif := []byte("eXIfMM\x00*\x00\x00\x00\x08\x00\x00")
+	chunk := make([]byte, 4+len(exif)+4)
+	binary.BigEndian.PutUint32(chunk, uint32(len(exif)-4))
+	copy(chunk[4:], exif)
+	binary.BigEndian.PutUint32(chunk[4+len(exif):], crc32.ChecksumIEEE(exif))
+	ihdrEnd := len(pngSignature) + 12 + 13
+	raw = append(append(append([]byte{}, raw[:ihdrEnd]...), chunk...), raw[ihdrEnd:]...)
+
+	upload, err := Prepare(raw)
+	assert.NoError(t, err)
+	assert.False(t, bytes.Contains(upload.Raw, []byte("tEXt")))
+	assert.False(t, bytes.Contains(upload.Raw, []byte("eXIf")))
+
+	_, img, err := Validate(upload.Raw)
+	assert.NoError(t, err)
+	assert.Equal(t, 150, img.Bounds().Dx())
+}
+
+func TestStripMetadata_WebP(t *testing.T) {
+	raw := readSample(t, "sample.webp")
+
+	// turn the simple sample into an extended WebP with EXIF
+	vp8x := []byte("VP8X\x0a\x00\x00\x00")
+	vp8x = append(vp8x, webpFlagEXIF, 0, 0, 0)
+	vp8x = append(vp8x, 149, 0, 0, 99, 0, 0)
+	exif := []byte("EXIF\x08\x00\x00\x00MM\x00*\x00\x00\x00\x08")
+	extended := append([]byte{}, raw[:12]...)
+	extended = append(extended, vp8x...)
+	extended = append(extended, raw[12:]...)
+	extended = append(extended, exif...)
+	binary.LittleEndian.PutUint32(extended[4:], uint32(len(extended)-8))
+	_, _, err := Validate(extended)
+	assert.NoError(t, err)
+
+	upload, err := Prepare(extended)
+	assert.NoError(t, err)
+	assert.False(t, bytes.Contains(upload.Raw, []byte("EXIF")))
+	assert.Equal(t, byte(0), upload.Raw[20]&webpFlagEXIF)
+	assert.Equal(t, len(raw)+len(vp8x), len(upload.Raw))
+
+	_, img, err := Validate(upload.Raw)
+	assert.NoError(t, err)
+	assert.Equal(t, 150, img.Bounds().Dx())
+}
+
+func TestStripMetadata_Malformed(t *testing.T) {
+	raw := readSample(t, "sample.png")
+
+	_, err := stripMetadata(ContentTypePNG, raw[:len(raw)-6])
+	assert.True(t, errors.Is(err, ErrInvalidImage))
+	_, err = stripMetadata(ContentTypeJPEG, raw)
+	assert.True(t, errors.Is(err, ErrInvalidImage))
+}
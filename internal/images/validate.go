@@ -0,0 +1,67 @@
+package images
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"net/http"
+
+	// decoders of the allowed types
+	_ "image/jpeg"
+	_ "image/png"
+
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	ContentTypeJPEG = "image/jpeg"
+	ContentTypePNG  = "image/png"
+	ContentTypeWebP = "image/webp"
+
+	// maxPixels keeps a small file declaring huge dimensions from using up
+	// all memory once decoded
+	maxPixels = 50 * 1000 * 1000
+)
+
+var (
+	ErrUnsupportedType = errors.New("image must be a JPEG, PNG or WebP")
+	ErrInvalidImage    = errors.New("image cannot be decoded")
+)
+
+// allowedTypes maps the sniffed content types to the name image.Decode
+// gives their format.
+var allowedTypes = map[string]string{
+	ContentTypeJPEG: "jpeg",
+	ContentTypePNG:  "png",
+	ContentTypeWebP: "webp",
+}
+
+// Validate sniffs the content type of raw and decodes it, returning the
+// content type and the image. It returns ErrUnsupportedType for anything
+// but JPEG, PNG and WebP, and ErrInvalidImage for files that do not decode.
+func Validate(raw []byte) (string, image.Image, error) {
+	contentType := http.DetectContentType(raw)
+	format, ok := allowedTypes[contentType]
+	if !ok {
+		return "", nil, ErrUnsupportedType
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(raw))
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxPixels {
+		return "", nil, fmt.Errorf("%w: %vx%v pixels", ErrInvalidImage, cfg.Width, cfg.Height)
+	}
+
+	img, decodedFormat, err := image.Decode(bytes.NewReader(raw))
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+	if decodedFormat != format {
+		return "", nil, ErrInvalidImage
+	}
+
+	return contentType, img, nil
+}
@@ -0,0 +1,61 @@
+package images
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/tj/assert"
+)
+
+func readSample(t *testing.T, name string) []byte {
+	t.Helper()
+
+	raw, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	assert.NoError(t, err)
+	return raw
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		file        string
+		contentType string
+		width       int
+		height      int
+	}{
+		{"sample.jpeg", ContentTypeJPEG, 280, 360},
+		{"sample.png", ContentTypePNG, 150, 100},
+		{"sample.webp", ContentTypeWebP, 150, 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			contentType, img, err := Validate(readSample(t, tt.file))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.contentType, contentType)
+			assert.Equal(t, tt.width, img.Bounds().Dx())
+			assert.Equal(t, tt.height, img.Bounds().Dy())
+		})
+	}
+}
+
+func TestValidate_Rejected(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  []byte
+		err  error
+	}{
+		{"text", []byte("hello"), ErrUnsupportedType},
+		{"empty", nil, ErrUnsupportedType},
+		{"gif", readSample(t, "sample.gif"), ErrUnsupportedType},
+		{"truncated jpeg", readSample(t, "truncated.jpeg"), ErrInvalidImage},
+		// looks like a PNG but is not one
+		{"png signature only", []byte("\x89PNG\r\n\x1a\ngarbage"), ErrInvalidImage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := Validate(tt.raw)
+			assert.True(t, errors.Is(err, tt.err), "got %v", err)
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package images
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+
+	"golang.org/x/image/draw"
+)
+
+// VariantSizes are the sizes, in pixels of the longest side, of the
+// resized copies stored with every image.
+var VariantSizes = []int{128, 512, 1024}
+
+const (
+	variantQuality = 85
+	// originalQuality is used for the originals that have to be re-encoded
+	originalQuality = 95
+)
+
+// Upload is a validated image ready to be stored, the original as sent and
+// its resized variants.
+type Upload struct {
+	ContentType string
+	Raw         []byte
+	Variants    []Variant
+}
+
+// Variant is a JPEG copy of an image no larger than Size on either side.
+type Variant struct {
+	Size int
+	Raw  []byte
+}
+
+// VariantContentType is the content type of every variant.
+const VariantContentType = ContentTypeJPEG
+
+// VariantKey returns the key a variant of the image stored at key is
+// stored at, next to the original.
+func VariantKey(key string, size int) string {
+	return fmt.Sprintf("%s_%d", key, size)
+}
+
+// Prepare validates raw and creates its variants, turned the way its EXIF
+// orientation says. Images are never upscaled, so variants of a small
+// image have its size.
+//
+// The original is stored without its metadata, photos often carry the
+// location they were taken at. A JPEG that relies on its EXIF orientation
+// is re-encoded turned instead, since stripping the orientation would show
+// it sideways.
+func Prepare(raw []byte) (Upload, error) {
+	contentType, img, err := Validate(raw)
+	if err != nil {
+		return Upload{}, err
+	}
+
+	orientation := 1
+	if contentType == ContentTypeJPEG {
+		orientation = exifOrientation(raw)
+	}
+	var original []byte
+	if orientation != 1 {
+		img = orient(img, orientation)
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: originalQuality}); err != nil {
+			return Upload{}, err
+		}
+		original = buf.Bytes()
+	} else {
+		original, err = stripMetadata(contentType, raw)
+		if err != nil {
+			return Upload{}, err
+		}
+	}
+
+	upload := Upload{
+		ContentType: contentType,
+		Raw:         original,
+	}
+	for _, size := range VariantSizes {
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, resize(img, size), &jpeg.Options{Quality: variantQuality}); err != nil {
+			return Upload{}, err
+		}
+		upload.Variants = append(upload.Variants, Variant{
+			Size: size,
+			Raw:  buf.Bytes(),
+		})
+	}
+
+	return upload, nil
+}
+
+// resize scales img so that its longest side is at most size, on a white
+// background since JPEG has no transparency.
+func resize(img image.Image, size int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > size || h > size {
+		if w >= h {
+			w, h = size, h*size/w
+		} else {
+			w, h = w*size/h, size
+		}
+	}
+	// very thin images would round down to nothing
+	if w == 0 {
+		w = 1
+	}
+	if h == 0 {
+		h = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Over, nil)
+	return dst
+}
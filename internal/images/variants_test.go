@@ -0,0 +1,112 @@
+package images
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+
+	"github.com/tj/assert"
+)
+
+// withEXIF inserts an EXIF segment holding the given orientation right
+// after the start of a JPEG.
+func withEXIF(raw []byte, orientation uint16) []byte {
+	var tiff bytes.Buffer
+	tiff.WriteString("II*\x00")
+	binary.Write(&tiff, binary.LittleEndian, uint32(8))
+	binary.Write(&tiff, binary.LittleEndian, uint16(1))
+	// SHORT value, padded to the four bytes of the entry
+	binary.Write(&tiff, binary.LittleEndian, []uint16{tagOrientation, 3})
+	binary.Write(&tiff, binary.LittleEndian, uint32(1))
+	binary.Write(&tiff, binary.LittleEndian, []uint16{orientation, 0})
+	binary.Write(&tiff, binary.LittleEndian, uint32(0))
+
+	return withSegment(raw, markerAPP1, append(append([]byte{}, exifHeader...), tiff.Bytes()...))
+}
+
+// withSegment inserts a segment right after the start of a JPEG.
+func withSegment(raw []byte, marker byte, payload []byte) []byte {
+	segment := []byte{0xff, marker, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	segment = append(segment, payload...)
+
+	out := append([]byte{}, raw[:2]...)
+	out = append(out, segment...)
+	return append(out, raw[2:]...)
+}
+
+// halfRed encodes a w by h JPEG whose left half is red and right half blue.
+func halfRed(t *testing.T, w, h int) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if x < w/2 {
+				img.Set(x, y, color.RGBA{R: 255, A: 255})
+			} else {
+				img.Set(x, y, color.RGBA{B: 255, A: 255})
+			}
+		}
+	}
+	var buf bytes.Buffer
+	assert.NoError(t, jpeg.Encode(&buf, img, &jpeg.Options{Quality: 100}))
+	return buf.Bytes()
+}
+
+func isRed(c color.Color) bool {
+	r, g, b, _ := c.RGBA()
+	return r > 0xc000 && g < 0x4000 && b < 0x4000
+}
+
+func TestPrepare(t *testing.T) {
+	raw := readSample(t, "sample.jpeg")
+	upload, err := Prepare(raw)
+	assert.NoError(t, err)
+	assert.Equal(t, ContentTypeJPEG, upload.ContentType)
+	assert.Len(t, upload.Variants, len(VariantSizes))
+
+	// the sample is 280x360, so only the smallest variant is scaled down
+	sizes := map[int][2]int{
+		128:  {99, 128},
+		512:  {280, 360},
+		1024: {280, 360},
+	}
+	for _, variant := range upload.Variants {
+		img, err := jpeg.Decode(bytes.NewReader(variant.Raw))
+		assert.NoError(t, err)
+		assert.Equal(t, sizes[variant.Size][0], img.Bounds().Dx())
+		assert.Equal(t, sizes[variant.Size][1], img.Bounds().Dy())
+	}
+
+	_, err = Prepare(readSample(t, "sample.gif"))
+	assert.Equal(t, ErrUnsupportedType, err)
+}
+
+func TestPrepare_Orientation(t *testing.T) {
+	raw := withEXIF(halfRed(t, 64, 32), 6)
+	assert.Equal(t, 6, exifOrientation(raw))
+
+	upload, err := Prepare(raw)
+	assert.NoError(t, err)
+
+	// turned clockwise, the red left half ends up on top
+	img, err := jpeg.Decode(bytes.NewReader(upload.Variants[0].Raw))
+	assert.NoError(t, err)
+	assert.Equal(t, 32, img.Bounds().Dx())
+	assert.Equal(t, 64, img.Bounds().Dy())
+	assert.True(t, isRed(img.At(16, 8)))
+	assert.False(t, isRed(img.At(16, 56)))
+}
+
+func TestExifOrientation(t *testing.T) {
+	raw := halfRed(t, 8, 8)
+	assert.Equal(t, 1, exifOrientation(raw))
+	assert.Equal(t, 3, exifOrientation(withEXIF(raw, 3)))
+	// out of range values are ignored
+	assert.Equal(t, 1, exifOrientation(withEXIF(raw, 9)))
+	assert.Equal(t, 1, exifOrientation([]byte("not a jpeg")))
+}
@@ -0,0 +1,173 @@
+// Package logging writes leveled logs as one JSON object per line, in the
+// format Cloud Logging parses from stdout.
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// String returns the Cloud Logging severity of the level.
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelWarn:
+		return "WARNING"
+	case LevelError:
+		return "ERROR"
+	default:
+		return fmt.Sprintf("LEVEL(%d)", int(l))
+	}
+}
+
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return LevelDebug, nil
+	case "info":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	default:
+		return 0, fmt.Errorf("unknown log level %q", s)
+	}
+}
+
+// Logger writes entries at or above its level to out. Loggers returned by
+// With share the writer of their parent.
+type Logger struct {
+	mu     *sync.Mutex
+	out    io.Writer
+	level  Level
+	fields []interface{}
+	now    func() time.Time
+}
+
+func New(out io.Writer, level Level) *Logger {
+	return &Logger{
+		mu:    &sync.Mutex{},
+		out:   out,
+		level: level,
+		now:   time.Now,
+	}
+}
+
+// With returns a logger that adds the key-value pairs to every entry.
+func (l *Logger) With(keyvals ...interface{}) *Logger {
+	child := *l
+	child.fields = append(append([]interface{}{}, l.fields...), keyvals...)
+	return &child
+}
+
+func (l *Logger) Enabled(level Level) bool {
+	return level >= l.level
+}
+
+func (l *Logger) Debug(msg string, keyvals ...interface{}) { l.Log(LevelDebug, msg, keyvals...) }
+func (l *Logger) Info(msg string, keyvals ...interface{})  { l.Log(LevelInfo, msg, keyvals...) }
+func (l *Logger) Warn(msg string, keyvals ...interface{})  { l.Log(LevelWarn, msg, keyvals...) }
+func (l *Logger) Error(msg string, keyvals ...interface{}) { l.Log(LevelError, msg, keyvals...) }
+
+// Log writes msg and the key-value pairs, like "businessId", id, as one
+// JSON object if level is enabled.
+func (l *Logger) Log(level Level, msg string, keyvals ...interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+
+	entry := map[string]interface{}{
+		"severity": level.String(),
+		"message":  msg,
+		"time":     l.now().UTC().Format(time.RFC3339Nano),
+	}
+	addFields(entry, l.fields)
+	addFields(entry, keyvals)
+
+	raw, err := json.Marshal(entry)
+	if err != nil {
+		raw, _ = json.Marshal(map[string]interface{}{
+			"severity": LevelError.String(),
+			"message":  fmt.Sprintf("cannot marshal log entry %q: %v", msg, err),
+		})
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.out.Write(append(raw, '\n'))
+}
+
+// addFields copies the key-value pairs into entry. A key without a value
+// is kept under "!BADKEY" rather than dropped.
+func addFields(entry map[string]interface{}, keyvals []interface{}) {
+	for i := 0; i < len(keyvals); i += 2 {
+		key, ok := keyvals[i].(string)
+		if !ok || i+1 == len(keyvals) {
+			entry["!BADKEY"] = keyvals[i]
+			i--
+			continue
+		}
+		value := keyvals[i+1]
+		switch v := value.(type) {
+		case error:
+			// errors marshal to {} otherwise
+			value = v.Error()
+		case time.Duration:
+			value = v.String()
+		}
+		entry[key] = value
+	}
+}
+
+var (
+	defaultMu     sync.Mutex
+	defaultLogger = New(os.Stdout, LevelInfo)
+)
+
+// Default is the logger used when a context has none.
+func Default() *Logger {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	return defaultLogger
+}
+
+func SetDefault(l *Logger) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultLogger = l
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying l, handlers log through it so
+// their entries share the request ID.
+func NewContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the logger of ctx, or the default logger.
+func FromContext(ctx context.Context) *Logger {
+	if l, ok := ctx.Value(contextKey{}).(*Logger); ok {
+		return l
+	}
+	return Default()
+}
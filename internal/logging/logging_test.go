@@ -0,0 +1,76 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tj/assert"
+)
+
+func TestLogger_Levels(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, LevelWarn)
+
+	logger.Debug("debug")
+	logger.Info("info")
+	logger.Warn("warn")
+	logger.Error("error")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 2)
+	assert.Contains(t, lines[0], `"severity":"WARNING"`)
+	assert.Contains(t, lines[1], `"severity":"ERROR"`)
+}
+
+func TestLogger_Fields(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, LevelDebug)
+	logger.now = func() time.Time { return time.Date(2022, 3, 1, 9, 30, 0, 0, time.UTC) }
+
+	child := logger.With("requestId", "abc")
+	child.Info("created business", "businessId", "b1", "error", errors.New("boom"), "took", 1500*time.Millisecond, "dangling")
+	// the parent is not changed by With
+	logger.Info("plain")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 2)
+
+	var entry map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+	assert.Equal(t, map[string]interface{}{
+		"severity":   "INFO",
+		"message":    "created business",
+		"time":       "2022-03-01T09:30:00Z",
+		"requestId":  "abc",
+		"businessId": "b1",
+		"error":      "boom",
+		"took":       "1.5s",
+		"!BADKEY":    "dangling",
+	}, entry)
+
+	assert.NotContains(t, lines[1], "requestId")
+}
+
+func TestParseLevel(t *testing.T) {
+	for s, want := range map[string]Level{"debug": LevelDebug, "INFO": LevelInfo, "warning": LevelWarn, " error ": LevelError} {
+		level, err := ParseLevel(s)
+		assert.NoError(t, err)
+		assert.Equal(t, want, level)
+	}
+
+	_, err := ParseLevel("verbose")
+	assert.Error(t, err)
+}
+
+func TestFromContext(t *testing.T) {
+	assert.Equal(t, Default(), FromContext(context.Background()))
+
+	logger := New(&bytes.Buffer{}, LevelInfo)
+	ctx := NewContext(context.Background(), logger)
+	assert.Equal(t, logger, FromContext(ctx))
+}
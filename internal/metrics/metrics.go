@@ -0,0 +1,112 @@
+// Package metrics collects the Prometheus metrics served on /metrics.
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "servizio"
+
+// Metrics holds the collectors of one server. They are registered on their
+// own registry so that tests can create as many as they need.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	inFlight        *prometheus.GaugeVec
+	storeCalls      *prometheus.CounterVec
+	storeErrors     *prometheus.CounterVec
+	storeDuration   *prometheus.HistogramVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests handled, by route, method and status code.",
+		}, []string{"route", "method", "code"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Time taken to handle HTTP requests, by route and method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method"}),
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "http_requests_in_flight",
+			Help:      "HTTP requests being handled, by route.",
+		}, []string{"route"}),
+		storeCalls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "firestore_calls_total",
+			Help:      "Calls made to the Firestore backed stores, by store and method.",
+		}, []string{"store", "method"}),
+		storeErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "firestore_errors_total",
+			Help:      "Calls to the Firestore backed stores that returned an error, by store and method.",
+		}, []string{"store", "method"}),
+		storeDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "firestore_call_duration_seconds",
+			Help:      "Time taken by calls to the Firestore backed stores, by store and method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"store", "method"}),
+	}
+
+	m.registry.MustRegister(
+		m.requests,
+		m.requestDuration,
+		m.inFlight,
+		m.storeCalls,
+		m.storeErrors,
+		m.storeDuration,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	return m
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// TrackRequest counts a request to route as in flight until the returned
+// func is called with the status code it was answered with.
+func (m *Metrics) TrackRequest(route, method string) func(status int) {
+	start := time.Now()
+	inFlight := m.inFlight.WithLabelValues(route)
+	inFlight.Inc()
+
+	return func(status int) {
+		inFlight.Dec()
+		m.requests.WithLabelValues(route, method, strconv.Itoa(status)).Inc()
+		m.requestDuration.WithLabelValues(route, method).Observe(time.Since(start).Seconds())
+	}
+}
+
+// ObserveStore counts the calls and errors of a store, it is an
+// observe.Func for the Instrument funcs of the dao packages.
+func (m *Metrics) ObserveStore(ctx context.Context, store, method string) (context.Context, func(err error)) {
+	start := time.Now()
+	m.storeCalls.WithLabelValues(store, method).Inc()
+
+	return ctx, func(err error) {
+		m.storeDuration.WithLabelValues(store, method).Observe(time.Since(start).Seconds())
+		if err != nil {
+			m.storeErrors.WithLabelValues(store, method).Inc()
+		}
+	}
+}
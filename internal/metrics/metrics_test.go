@@ -0,0 +1,55 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tj/assert"
+)
+
+// scrape returns what Prometheus would read from m.
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	raw, err := ioutil.ReadAll(w.Result().Body)
+	assert.NoError(t, err)
+	return string(raw)
+}
+
+func TestTrackRequest(t *testing.T) {
+	m := New()
+
+	done := m.TrackRequest("/businesses/", http.MethodGet)
+	assert.Contains(t, scrape(t, m), `servizio_http_requests_in_flight{route="/businesses/"} 1`)
+	done(http.StatusOK)
+	m.TrackRequest("/businesses/", http.MethodGet)(http.StatusNotFound)
+	m.TrackRequest("/businesses/", http.MethodGet)(http.StatusNotFound)
+
+	out := scrape(t, m)
+	assert.Contains(t, out, `servizio_http_requests_in_flight{route="/businesses/"} 0`)
+	assert.Contains(t, out, `servizio_http_requests_total{code="200",method="GET",route="/businesses/"} 1`)
+	assert.Contains(t, out, `servizio_http_requests_total{code="404",method="GET",route="/businesses/"} 2`)
+	assert.Contains(t, out, `servizio_http_request_duration_seconds_count{method="GET",route="/businesses/"} 3`)
+}
+
+func TestObserveStore(t *testing.T) {
+	m := New()
+	ctx := context.Background()
+
+	_, done := m.ObserveStore(ctx, "appointmentdao", "Create")
+	done(nil)
+	_, done = m.ObserveStore(ctx, "appointmentdao", "Create")
+	done(errors.New("deadline exceeded"))
+
+	out := scrape(t, m)
+	assert.Contains(t, out, `servizio_firestore_calls_total{method="Create",store="appointmentdao"} 2`)
+	assert.Contains(t, out, `servizio_firestore_errors_total{method="Create",store="appointmentdao"} 1`)
+	assert.Contains(t, out, `servizio_firestore_call_duration_seconds_count{method="Create",store="appointmentdao"} 2`)
+}
@@ -0,0 +1,42 @@
+// Package observe lets metrics and tracing hook into store calls without
+// the stores depending on either.
+package observe
+
+import (
+	"context"
+	"errors"
+)
+
+// Func is called when a call to method of store starts. It returns the
+// context to make the call with and a func to call with the error the
+// call returned.
+type Func func(ctx context.Context, store, method string) (context.Context, func(err error))
+
+// Chain returns a Func calling each of fns, the first one is the
+// outermost.
+func Chain(fns ...Func) Func {
+	return func(ctx context.Context, store, method string) (context.Context, func(err error)) {
+		dones := make([]func(err error), len(fns))
+		for i, fn := range fns {
+			ctx, dones[i] = fn(ctx, store, method)
+		}
+		return ctx, func(err error) {
+			for i := len(dones) - 1; i >= 0; i-- {
+				dones[i](err)
+			}
+		}
+	}
+}
+
+// Failure returns err unless it is one of expected, the errors a store
+// answers with for what is not there or may not be done, such as not
+// found. Those come with normal traffic, so they are not reported as
+// failures of the store.
+func Failure(err error, expected ...error) error {
+	for _, e := range expected {
+		if errors.Is(err, e) {
+			return nil
+		}
+	}
+	return err
+}
@@ -0,0 +1,48 @@
+package observe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/tj/assert"
+)
+
+type ctxKey string
+
+func TestChain(t *testing.T) {
+	var calls []string
+	fn := func(name string) Func {
+		return func(ctx context.Context, store, method string) (context.Context, func(err error)) {
+			calls = append(calls, name+" start "+store+"."+method)
+			ctx = context.WithValue(ctx, ctxKey(name), true)
+			return ctx, func(err error) {
+				calls = append(calls, name+" done "+err.Error())
+			}
+		}
+	}
+
+	ctx, done := Chain(fn("outer"), fn("inner"))(context.Background(), "businessdao", "GetBusiness")
+	// the context carries what every func added
+	assert.Equal(t, true, ctx.Value(ctxKey("outer")))
+	assert.Equal(t, true, ctx.Value(ctxKey("inner")))
+
+	done(errors.New("boom"))
+	assert.Equal(t, []string{
+		"outer start businessdao.GetBusiness",
+		"inner start businessdao.GetBusiness",
+		"inner done boom",
+		"outer done boom",
+	}, calls)
+}
+
+func TestFailure(t *testing.T) {
+	notFound := errors.New("not found")
+	failed := errors.New("deadline exceeded")
+
+	assert.NoError(t, Failure(nil, notFound))
+	assert.NoError(t, Failure(notFound, notFound))
+	assert.NoError(t, Failure(fmt.Errorf("business: %w", notFound), notFound))
+	assert.Equal(t, failed, Failure(failed, notFound))
+}
@@ -0,0 +1,127 @@
+package pagination
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Input selects a page of a list query.
+type Input struct {
+	// Limit is the maximum number of documents, zero means no limit
+	Limit int
+	// StartAfter is the cursor returned with the previous page
+	StartAfter string
+}
+
+// Cursor is the position of the last document of a page, the values of
+// the fields the list is ordered by with the document ID last. It is
+// passed around encoded, so that clients treat it as opaque.
+type Cursor struct {
+	// Date is set for lists ordered by a date before the ID
+	Date time.Time
+	ID   string
+}
+
+// cursorJSON is how a Cursor is encoded.
+type cursorJSON struct {
+	Date *time.Time `json:"date,omitempty"`
+	ID   string     `json:"id"`
+}
+
+// Encode returns the cursor as passed to clients and back as StartAfter.
+func (c Cursor) Encode() string {
+	out := cursorJSON{ID: c.ID}
+	if !c.Date.IsZero() {
+		out.Date = &c.Date
+	}
+	raw, err := json.Marshal(out)
+	if err != nil {
+		// a string and a time always marshal
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// DecodeCursor reads a cursor given by Encode for a list ordered by a
+// date before the ID if byDate, or by the ID alone. It returns
+// ErrInvalidCursor for anything else, such as a cursor of another list.
+func DecodeCursor(encoded string, byDate bool) (Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	var in cursorJSON
+	if err := json.Unmarshal(raw, &in); err != nil || in.ID == "" || (in.Date != nil) != byDate {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	cursor := Cursor{ID: in.ID}
+	if in.Date != nil {
+		cursor.Date = *in.Date
+	}
+	return cursor, nil
+}
+
+// GetPage runs query from the position given by input.StartAfter and
+// returns at most input.Limit snapshots. next is the cursor to pass as
+// StartAfter for the following page, or empty if this is the last one.
+//
+// The query must be ordered by dateField, unless it is empty, and then by
+// document ID, in the same direction. The cursor holds the values of
+// those fields, so a page can follow one whose last document is gone.
+func GetPage(ctx context.Context, query firestore.Query, input Input, dateField string) (snapshots []*firestore.DocumentSnapshot, next string, err error) {
+	if input.StartAfter != "" {
+		cursor, err := DecodeCursor(input.StartAfter, dateField != "")
+		if err != nil {
+			return nil, "", err
+		}
+		if dateField != "" {
+			query = query.StartAfter(cursor.Date, cursor.ID)
+		} else {
+			query = query.StartAfter(cursor.ID)
+		}
+	}
+	if input.Limit > 0 {
+		// fetch one more than asked to know if there is a next page
+		query = query.Limit(input.Limit + 1)
+	}
+
+	snapshots, err = query.Documents(ctx).GetAll()
+	if err != nil {
+		return nil, "", err
+	}
+	if input.Limit > 0 && len(snapshots) > input.Limit {
+		snapshots = snapshots[:input.Limit]
+		last := snapshots[len(snapshots)-1]
+		cursor := Cursor{ID: last.Ref.ID}
+		if dateField != "" {
+			value, err := last.DataAt(dateField)
+			date, ok := value.(time.Time)
+			if err != nil || !ok {
+				return nil, "", fmt.Errorf("pagination: %v of %v is not a date", dateField, last.Ref.ID)
+			}
+			cursor.Date = date
+		}
+		next = cursor.Encode()
+	}
+
+	return snapshots, next, nil
+}
+
+// PageEnd is GetPage for stores that hold their documents in memory. Given
+// the n documents ordered after the cursor, it returns how many of them
+// belong to the page and whether another page follows.
+func PageEnd(n int, input Input) (end int, more bool) {
+	if input.Limit > 0 && n > input.Limit {
+		return input.Limit, true
+	}
+	return n, false
+}
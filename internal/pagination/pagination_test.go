@@ -0,0 +1,39 @@
+package pagination
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tj/assert"
+)
+
+func TestCursor(t *testing.T) {
+	date := time.Date(2032, 12, 2, 9, 0, 0, 1000, time.UTC)
+	cursor, err := DecodeCursor(Cursor{ID: "foo", Date: date}.Encode(), true)
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", cursor.ID)
+	assert.True(t, date.Equal(cursor.Date))
+
+	cursor, err = DecodeCursor(Cursor{ID: "foo"}.Encode(), false)
+	assert.NoError(t, err)
+	assert.Equal(t, Cursor{ID: "foo"}, cursor)
+}
+
+func TestDecodeCursor_Invalid(t *testing.T) {
+	tests := map[string]struct {
+		cursor string
+		byDate bool
+	}{
+		"not base64":     {"%%", false},
+		"not json":       {"Zm9v", false},
+		"without id":     {Cursor{}.Encode(), false},
+		"without date":   {Cursor{ID: "foo"}.Encode(), true},
+		"date by the id": {Cursor{ID: "foo", Date: time.Now()}.Encode(), false},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := DecodeCursor(tt.cursor, tt.byDate)
+			assert.Equal(t, ErrInvalidCursor, err)
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/devduck123/servizio-be/internal/appointmentdao"
+	"github.com/devduck123/servizio-be/internal/businessdao"
+	"github.com/devduck123/servizio-be/internal/clientdao"
+)
+
+var errForbidden = errors.New("you are not allowed to modify this resource")
+
+// owns reports whether user is the owner recorded on a resource. Resources
+// created before owners were recorded belong to nobody.
+func owns(user User, ownerID string) bool {
+	return user.ID != "" && user.ID == ownerID
+}
+
+// appointmentActor returns which party of an appointment between the given
+// business and client the user is. ok is false if the user owns neither.
+func (s *Server) appointmentActor(ctx context.Context, user User, businessID, clientID string) (appointmentdao.Actor, bool, error) {
+	if businessID != "" {
+		business, err := s.businessDao.GetBusiness(ctx, businessID)
+		if err != nil && !errors.Is(err, businessdao.ErrBusinessNotFound) {
+			return "", false, err
+		}
+		if business != nil && owns(user, business.UserID) {
+			return appointmentdao.ActorBusiness, true, nil
+		}
+	}
+
+	if clientID != "" {
+		client, err := s.clientDao.GetClient(ctx, clientID)
+		if err != nil && !errors.Is(err, clientdao.ErrClientNotFound) {
+			return "", false, err
+		}
+		if client != nil && owns(user, client.UserID) {
+			return appointmentdao.ActorClient, true, nil
+		}
+	}
+
+	return "", false, nil
+}
+
+// authorizeAppointment is appointmentActor for handlers, it writes the
+// error response and returns false if the user is not a party.
+func (s *Server) authorizeAppointment(w http.ResponseWriter, r *http.Request, user User, businessID, clientID string) (appointmentdao.Actor, bool) {
+	actor, ok, err := s.appointmentActor(r.Context(), user, businessID, clientID)
+	if err != nil {
+		writeErrorJSON(w, http.StatusInternalServerError, err)
+		return "", false
+	}
+	if !ok {
+		writeErrorJSON(w, http.StatusForbidden, errForbidden)
+		return "", false
+	}
+
+	return actor, true
+}
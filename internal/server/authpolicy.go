@@ -0,0 +1,89 @@
+package server
+
+import (
+	"errors"
+	"strings"
+
+	"firebase.google.com/go/v4/auth"
+)
+
+var (
+	errMissingCredentials = errors.New("missing credentials")
+	errNotBearer          = errors.New("authorization header must use the Bearer scheme")
+	errProviderRejected   = errors.New("sign in provider not accepted")
+	errEmailNotVerified   = errors.New("email not verified")
+)
+
+// AuthPolicy decides which verified tokens Authenticate lets through.
+type AuthPolicy struct {
+	// Providers are the accepted firebase.sign_in_provider values, such as
+	// "password", "phone", "google.com" or "anonymous". Empty accepts any.
+	Providers []string
+	// RequireEmailVerified rejects tokens carrying an email that is not
+	// verified. Tokens without an email, like phone sign-ins, pass.
+	RequireEmailVerified bool
+	// RequireBearer rejects Authorization headers without the "Bearer "
+	// prefix, otherwise the prefix is optional.
+	RequireBearer bool
+}
+
+// DefaultAuthPolicy accepts email, phone and Google sign-ins, but not
+// anonymous users, and only verified emails.
+func DefaultAuthPolicy() AuthPolicy {
+	return AuthPolicy{
+		Providers:            []string{"password", "phone", "google.com"},
+		RequireEmailVerified: true,
+	}
+}
+
+// WithAuthPolicy replaces DefaultAuthPolicy.
+func WithAuthPolicy(policy AuthPolicy) Option {
+	return func(s *Server) {
+		s.authPolicy = policy
+	}
+}
+
+// idToken returns the token in an Authorization header.
+func (p AuthPolicy) idToken(header string) (string, error) {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return "", errMissingCredentials
+	}
+
+	const bearer = "bearer "
+	if len(header) > len(bearer) && strings.EqualFold(header[:len(bearer)], bearer) {
+		return strings.TrimSpace(header[len(bearer):]), nil
+	}
+	if p.RequireBearer {
+		return "", errNotBearer
+	}
+
+	return header, nil
+}
+
+// check returns why the policy rejects a verified token, or nil.
+func (p AuthPolicy) check(token *auth.Token) error {
+	if len(p.Providers) > 0 {
+		accepted := false
+		for _, provider := range p.Providers {
+			if provider == token.Firebase.SignInProvider {
+				accepted = true
+				break
+			}
+		}
+		if !accepted {
+			return errProviderRejected
+		}
+	}
+
+	if p.RequireEmailVerified {
+		email, _ := token.Claims["email"].(string)
+		// the claim is missing for some providers, which counts as unverified
+		emailVerified, _ := token.Claims["email_verified"].(bool)
+		if email != "" && !emailVerified {
+			return errEmailNotVerified
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,124 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/devduck123/servizio-be/internal/authtest"
+	"github.com/devduck123/servizio-be/internal/authtoken"
+	"github.com/tj/assert"
+)
+
+// authenticated answers 200 with the ID of the user Authenticate let in.
+func authenticated(w http.ResponseWriter, r *http.Request) {
+	user, err := UserFromContext(r.Context())
+	if err != nil {
+		writeErrorJSON(w, http.StatusUnauthorized, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, user.ID)
+}
+
+func mintToken(t *testing.T, input authtoken.MintInput) string {
+	t.Helper()
+
+	token, err := authtest.Verifier(projectID).Mint(input)
+	assert.NoError(t, err)
+	return token
+}
+
+func TestAuthenticate_Providers(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  authtoken.MintInput
+		status int
+	}{
+		{"verified email", authtoken.MintInput{UID: "foo", Email: "foo@test.com", EmailVerified: true, SignInProvider: "password"}, http.StatusOK},
+		{"unverified email", authtoken.MintInput{UID: "foo", Email: "foo@test.com", SignInProvider: "password"}, http.StatusUnauthorized},
+		{"phone without email claim", authtoken.MintInput{UID: "foo", SignInProvider: "phone"}, http.StatusOK},
+		{"google", authtoken.MintInput{UID: "foo", Email: "foo@gmail.com", EmailVerified: true, SignInProvider: "google.com"}, http.StatusOK},
+		{"anonymous", authtoken.MintInput{UID: "foo", SignInProvider: "anonymous"}, http.StatusUnauthorized},
+		{"unknown provider", authtoken.MintInput{UID: "foo", SignInProvider: "github.com"}, http.StatusUnauthorized},
+		{"no provider", authtoken.MintInput{UID: "foo"}, http.StatusUnauthorized},
+	}
+
+	server := NewServer(nil, nil, nil, nil, nil, WithTokenVerifier(authtest.Verifier(projectID)))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("Authorization", "Bearer "+mintToken(t, tt.input))
+
+			server.Authenticate(authenticated)(w, r)
+			assert.Equal(t, tt.status, w.Result().StatusCode)
+		})
+	}
+}
+
+func TestAuthenticate_CustomPolicy(t *testing.T) {
+	server := NewServer(nil, nil, nil, nil, nil,
+		WithTokenVerifier(authtest.Verifier(projectID)),
+		WithAuthPolicy(AuthPolicy{
+			Providers: []string{"anonymous", "password"},
+		}),
+	)
+
+	tests := []struct {
+		name   string
+		input  authtoken.MintInput
+		status int
+	}{
+		{"anonymous", authtoken.MintInput{UID: "foo", SignInProvider: "anonymous"}, http.StatusOK},
+		{"unverified email", authtoken.MintInput{UID: "foo", Email: "foo@test.com", SignInProvider: "password"}, http.StatusOK},
+		{"phone", authtoken.MintInput{UID: "foo", SignInProvider: "phone"}, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("Authorization", mintToken(t, tt.input))
+
+			server.Authenticate(authenticated)(w, r)
+			assert.Equal(t, tt.status, w.Result().StatusCode)
+		})
+	}
+}
+
+func TestAuthenticate_Header(t *testing.T) {
+	token := mintToken(t, authtoken.MintInput{UID: "foo", SignInProvider: "phone"})
+	expired := mintToken(t, authtoken.MintInput{UID: "foo", SignInProvider: "phone", ExpiresIn: -time.Minute})
+
+	tests := []struct {
+		name          string
+		header        string
+		requireBearer bool
+		status        int
+	}{
+		{"missing", "", false, http.StatusUnauthorized},
+		{"bearer", "Bearer " + token, false, http.StatusOK},
+		{"lowercase bearer", "bearer " + token, true, http.StatusOK},
+		{"bare token", token, false, http.StatusOK},
+		{"bare token with bearer required", token, true, http.StatusUnauthorized},
+		{"garbage", "Bearer foo", false, http.StatusUnauthorized},
+		{"expired", "Bearer " + expired, false, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := DefaultAuthPolicy()
+			policy.RequireBearer = tt.requireBearer
+			server := NewServer(nil, nil, nil, nil, nil,
+				WithTokenVerifier(authtest.Verifier(projectID)),
+				WithAuthPolicy(policy),
+			)
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("Authorization", tt.header)
+
+			server.Authenticate(authenticated)(w, r)
+			assert.Equal(t, tt.status, w.Result().StatusCode)
+		})
+	}
+}
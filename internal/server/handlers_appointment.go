@@ -5,14 +5,19 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"path"
 	"strings"
 	"time"
 
 	"github.com/devduck123/servizio-be/internal/appointmentdao"
+	"github.com/devduck123/servizio-be/internal/availability"
+	"github.com/devduck123/servizio-be/internal/businessdao"
+	"github.com/devduck123/servizio-be/internal/clientdao"
+	"github.com/devduck123/servizio-be/internal/logging"
 )
 
 func (s *Server) GetAppointment(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("GetAppointment called on:", r.URL.Path)
+	logging.FromContext(r.Context()).Debug("GetAppointment called", "path", r.URL.Path)
 
 	id := strings.TrimPrefix(r.URL.Path, "/appointments/")
 
@@ -33,37 +38,79 @@ func (s *Server) GetAppointment(w http.ResponseWriter, r *http.Request) {
 // TODO: NOTE THAT GETALLAPPOINTMENTS MAY NOT WANT TO
 // TO RETURN ALL APPOINTMENTS IN GENERAL???
 func (s *Server) GetAllAppointments(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("GetAllAppointments called on:", r.URL.Path, "on", r.URL.RawQuery)
+	logging.FromContext(r.Context()).Debug("GetAllAppointments called", "path", r.URL.Path, "query", r.URL.RawQuery)
 
 	clientID := r.URL.Query().Get("client")
 	businessID := r.URL.Query().Get("business")
-	fmt.Println("clientID:", clientID)
-	fmt.Println("businessID:", businessID)
 
 	input := appointmentdao.GetAllAppointmentsInput{
 		BusinessID: businessID,
 		ClientID:   clientID,
+		Status:     appointmentdao.Status(r.URL.Query().Get("status")),
+	}
+	if input.Status != "" && !input.Status.IsValid() {
+		writeErrorJSON(w, http.StatusBadRequest, errors.New("invalid status"))
+		return
 	}
 
-	allAppointments, err := s.appointmentDao.GetAllAppointments(r.Context(), input)
+	var err error
+	if from := r.URL.Query().Get("from"); from != "" {
+		input.From, err = time.Parse(time.RFC3339, from)
+		if err != nil {
+			writeErrorJSON(w, http.StatusBadRequest, errors.New("from must be an RFC 3339 date"))
+			return
+		}
+	}
+	if to := r.URL.Query().Get("to"); to != "" {
+		input.To, err = time.Parse(time.RFC3339, to)
+		if err != nil {
+			writeErrorJSON(w, http.StatusBadRequest, errors.New("to must be an RFC 3339 date"))
+			return
+		}
+	}
+	if !input.From.IsZero() && !input.To.IsZero() && !input.To.After(input.From) {
+		writeErrorJSON(w, http.StatusBadRequest, errors.New("to must be after from"))
+		return
+	}
+
+	switch sort := r.URL.Query().Get("sort"); sort {
+	case "", "asc":
+	case "desc":
+		input.Descending = true
+	default:
+		writeErrorJSON(w, http.StatusBadRequest, errors.New("sort must be asc or desc"))
+		return
+	}
+
+	input.Page, err = pageFromRequest(r)
 	if err != nil {
-		writeErrorJSON(w, http.StatusInternalServerError, err)
+		writeErrorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+
+	allAppointments, next, err := s.appointmentDao.GetAllAppointments(r.Context(), input)
+	if err != nil {
+		writeListError(w, err)
 		return
 	}
 
-	writeJSON(w, http.StatusOK, allAppointments)
+	writeJSON(w, http.StatusOK, listResponse{
+		Items:      allAppointments,
+		NextCursor: next,
+	})
 }
 
 type AppointmentCreateInput struct {
 	ClientID   string    `json:"clientId"`
 	BusinessID string    `json:"businessId"`
 	Date       time.Time `json:"date"`
+	EndDate    time.Time `json:"endDate"`
 }
 
 func (s *Server) CreateAppointment(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("CreateAppointment called on:", r.URL.Path)
+	logging.FromContext(r.Context()).Debug("CreateAppointment called", "path", r.URL.Path)
 
-	_, err := UserFromContext(r.Context())
+	user, err := UserFromContext(r.Context())
 	if err != nil {
 		writeErrorJSON(w, http.StatusUnauthorized, err)
 		return
@@ -76,7 +123,7 @@ func (s *Server) CreateAppointment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	fmt.Printf("%+v\n", appointmentCreateInput)
+	logging.FromContext(r.Context()).Debug("decoded input", "input", appointmentCreateInput)
 
 	if strings.TrimSpace(appointmentCreateInput.ClientID) == "" {
 		writeErrorJSON(w, http.StatusBadRequest, errors.New("clientID cannot be empty"))
@@ -86,18 +133,213 @@ func (s *Server) CreateAppointment(w http.ResponseWriter, r *http.Request) {
 		writeErrorJSON(w, http.StatusBadRequest, errors.New("businessID cannot be empty"))
 		return
 	}
-	if appointmentCreateInput.Date.Before(time.Now()) {
-		writeErrorJSON(w, http.StatusBadRequest, errors.New("date invalid"))
+	if !s.validateBooking(w, r, appointmentCreateInput.BusinessID, appointmentCreateInput.Date, appointmentCreateInput.EndDate) {
 		return
 	}
+	// either the client books for themselves or the business books for them
+	actor, ok := s.authorizeAppointment(w, r, user, appointmentCreateInput.BusinessID, appointmentCreateInput.ClientID)
+	if !ok {
+		return
+	}
+	if actor == appointmentdao.ActorBusiness {
+		// only a client that exists can see or cancel the appointment
+		if _, err := s.clientDao.GetClient(r.Context(), appointmentCreateInput.ClientID); err != nil {
+			if errors.Is(err, clientdao.ErrClientNotFound) {
+				writeErrorJSON(w, http.StatusNotFound, err)
+				return
+			}
+
+			writeErrorJSON(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
 
 	appointmentToCreateInput := appointmentdao.CreateInput{
 		ClientID:   appointmentCreateInput.ClientID,
 		BusinessID: appointmentCreateInput.BusinessID,
 		Date:       appointmentCreateInput.Date,
+		EndDate:    appointmentCreateInput.EndDate,
 	}
 	appointment, err := s.appointmentDao.Create(r.Context(), appointmentToCreateInput)
 	if err != nil {
+		if errors.Is(err, appointmentdao.ErrAppointmentConflict) {
+			writeErrorJSON(w, http.StatusConflict, err)
+			return
+		}
+
+		writeErrorJSON(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, appointment)
+}
+
+// validateBooking checks that [date, endDate) is a future interval no longer
+// than appointmentdao.MaxDuration within the opening hours of the business.
+// It writes the error response and returns false if it is not.
+func (s *Server) validateBooking(w http.ResponseWriter, r *http.Request, businessID string, date, endDate time.Time) bool {
+	if date.Before(time.Now()) {
+		writeErrorJSON(w, http.StatusBadRequest, errors.New("date invalid"))
+		return false
+	}
+	if !endDate.After(date) {
+		writeErrorJSON(w, http.StatusBadRequest, errors.New("endDate must be after date"))
+		return false
+	}
+	if endDate.Sub(date) > appointmentdao.MaxDuration {
+		writeErrorJSON(w, http.StatusBadRequest, fmt.Errorf("appointment cannot last more than %v", appointmentdao.MaxDuration))
+		return false
+	}
+
+	business, err := s.businessDao.GetBusiness(r.Context(), businessID)
+	if err != nil {
+		if errors.Is(err, businessdao.ErrBusinessNotFound) {
+			writeErrorJSON(w, http.StatusNotFound, err)
+			return false
+		}
+
+		writeErrorJSON(w, http.StatusInternalServerError, err)
+		return false
+	}
+	if business.OpeningHours != nil {
+		within, err := availability.Within(*business.OpeningHours, date, endDate)
+		if err != nil {
+			writeErrorJSON(w, http.StatusInternalServerError, err)
+			return false
+		}
+		if !within {
+			writeErrorJSON(w, http.StatusBadRequest, errors.New("appointment is outside of opening hours"))
+			return false
+		}
+	}
+
+	return true
+}
+
+type AppointmentRescheduleInput struct {
+	Date    time.Time `json:"date"`
+	EndDate time.Time `json:"endDate"`
+}
+
+func (s *Server) RescheduleAppointment(w http.ResponseWriter, r *http.Request) {
+	logging.FromContext(r.Context()).Debug("RescheduleAppointment called", "path", r.URL.Path)
+
+	ctx := r.Context()
+
+	user, err := UserFromContext(ctx)
+	if err != nil {
+		writeErrorJSON(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	var appointmentRescheduleInput AppointmentRescheduleInput
+	err = json.NewDecoder(r.Body).Decode(&appointmentRescheduleInput)
+	if err != nil {
+		writeErrorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+
+	id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/appointments/"), "/")
+	appointment, err := s.appointmentDao.GetAppointment(ctx, id)
+	if err != nil {
+		if errors.Is(err, appointmentdao.ErrAppointmentNotFound) {
+			writeErrorJSON(w, http.StatusNotFound, err)
+			return
+		}
+
+		writeErrorJSON(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if _, ok := s.authorizeAppointment(w, r, user, appointment.BusinessID, appointment.ClientID); !ok {
+		return
+	}
+	if !s.validateBooking(w, r, appointment.BusinessID, appointmentRescheduleInput.Date, appointmentRescheduleInput.EndDate) {
+		return
+	}
+
+	appointment, err = s.appointmentDao.Reschedule(ctx, id, appointmentdao.RescheduleInput{
+		Date:    appointmentRescheduleInput.Date,
+		EndDate: appointmentRescheduleInput.EndDate,
+		UserID:  user.ID,
+	})
+	if err != nil {
+		if errors.Is(err, appointmentdao.ErrAppointmentNotFound) {
+			writeErrorJSON(w, http.StatusNotFound, err)
+			return
+		}
+		if errors.Is(err, appointmentdao.ErrAppointmentConflict) || errors.Is(err, appointmentdao.ErrAppointmentClosed) {
+			writeErrorJSON(w, http.StatusConflict, err)
+			return
+		}
+
+		writeErrorJSON(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, appointment)
+}
+
+// appointmentStatusActions maps the action at the end of
+// POST /appointments/{id}/{action} to the status it moves to.
+var appointmentStatusActions = map[string]appointmentdao.Status{
+	"confirm":  appointmentdao.StatusConfirmed,
+	"cancel":   appointmentdao.StatusCancelled,
+	"complete": appointmentdao.StatusCompleted,
+	"no-show":  appointmentdao.StatusNoShow,
+}
+
+func (s *Server) ChangeAppointmentStatus(w http.ResponseWriter, r *http.Request) {
+	logging.FromContext(r.Context()).Debug("ChangeAppointmentStatus called", "path", r.URL.Path)
+
+	ctx := r.Context()
+
+	user, err := UserFromContext(ctx)
+	if err != nil {
+		writeErrorJSON(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	trimmedURL := strings.TrimSuffix(r.URL.Path, "/")
+	action := path.Base(trimmedURL)
+	newStatus, ok := appointmentStatusActions[action]
+	if !ok {
+		writeErrorJSON(w, http.StatusNotFound, fmt.Errorf("unknown action %v", action))
+		return
+	}
+	id := strings.TrimSuffix(strings.TrimPrefix(trimmedURL, "/appointments/"), "/"+action)
+
+	appointment, err := s.appointmentDao.GetAppointment(ctx, id)
+	if err != nil {
+		if errors.Is(err, appointmentdao.ErrAppointmentNotFound) {
+			writeErrorJSON(w, http.StatusNotFound, err)
+			return
+		}
+
+		writeErrorJSON(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	actor, ok := s.authorizeAppointment(w, r, user, appointment.BusinessID, appointment.ClientID)
+	if !ok {
+		return
+	}
+
+	appointment, err = s.appointmentDao.UpdateStatus(ctx, id, appointmentdao.UpdateStatusInput{
+		Status: newStatus,
+		Actor:  actor,
+		UserID: user.ID,
+	})
+	if err != nil {
+		if errors.Is(err, appointmentdao.ErrAppointmentNotFound) {
+			writeErrorJSON(w, http.StatusNotFound, err)
+			return
+		}
+		if errors.Is(err, appointmentdao.ErrInvalidTransition) {
+			writeErrorJSON(w, http.StatusConflict, err)
+			return
+		}
+
 		writeErrorJSON(w, http.StatusInternalServerError, err)
 		return
 	}
@@ -106,10 +348,32 @@ func (s *Server) CreateAppointment(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) DeleteAppointment(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("DeleteAppointment called on:", r.URL.Path)
+	logging.FromContext(r.Context()).Debug("DeleteAppointment called", "path", r.URL.Path)
+
+	ctx := r.Context()
+
+	user, err := UserFromContext(ctx)
+	if err != nil {
+		writeErrorJSON(w, http.StatusUnauthorized, err)
+		return
+	}
 
 	id := strings.TrimPrefix(r.URL.Path, "/appointments/")
-	err := s.appointmentDao.Delete(r.Context(), id)
+	appointment, err := s.appointmentDao.GetAppointment(ctx, id)
+	if err != nil {
+		if errors.Is(err, appointmentdao.ErrAppointmentNotFound) {
+			writeErrorJSON(w, http.StatusNotFound, err)
+			return
+		}
+
+		writeErrorJSON(w, http.StatusInternalServerError, err)
+		return
+	}
+	if _, ok := s.authorizeAppointment(w, r, user, appointment.BusinessID, appointment.ClientID); !ok {
+		return
+	}
+
+	err = s.appointmentDao.Delete(ctx, id)
 	if err != nil {
 		if errors.Is(err, appointmentdao.ErrAppointmentNotFound) {
 			writeErrorJSON(w, http.StatusNotFound, err)
@@ -11,38 +11,235 @@ import (
 	"testing"
 	"time"
 
-	"cloud.google.com/go/firestore"
 	"github.com/devduck123/servizio-be/internal/appointmentdao"
-	"github.com/google/uuid"
+	"github.com/devduck123/servizio-be/internal/businessdao"
+	"github.com/devduck123/servizio-be/internal/clientdao"
 	"github.com/tj/assert"
 )
 
-func createTestAppointmentDao(ctx context.Context, t *testing.T, keepCollection ...bool) *appointmentdao.Dao {
+func createTestAppointmentDao(ctx context.Context, t *testing.T) appointmentdao.Store {
 	t.Helper()
 
-	client, err := firestore.NewClient(ctx, projectID)
+	return appointmentdao.NewMemoryStore()
+}
+
+func TestCreateAppointment(t *testing.T) {
+	ctx := context.Background()
+	businessDao := createTestBusinessDao(ctx, t)
+	clientDao := createTestClientDao(ctx, t)
+	dao := createTestAppointmentDao(ctx, t)
+	server := NewServer(businessDao, clientDao, dao, nil, nil)
+
+	business, err := businessDao.Create(ctx, businessdao.CreateInput{
+		Name:   "bar",
+		UserID: "owner",
+	})
+	assert.NoError(t, err)
+	client, err := clientDao.Create(ctx, clientdao.CreateInput{
+		FirstName: "foo",
+		LastName:  "baz",
+		UserID:    "client",
+	})
 	assert.NoError(t, err)
-	appointmentCollection := fmt.Sprintf("appointment-%v", uuid.New())
-	dao := appointmentdao.NewDao(client, appointmentCollection)
 
-	cleanUp := true
-	for _, kc := range keepCollection {
-		if kc {
-			cleanUp = false
-			break
-		}
-	}
+	w := httptest.NewRecorder()
+	body := bytes.NewReader([]byte(fmt.Sprintf(`{
+		"clientId":"%v",
+		"businessId":"%v",
+		"date":"2032-12-02T15:04:05+07:00",
+		"endDate":"2032-12-02T16:04:05+07:00"
+	}`, client.ID, business.ID)))
+	r := httptest.NewRequest(http.MethodPost, "/", body)
+	r = r.WithContext(ContextWithUser(ctx, User{ID: "client"}))
+	server.CreateAppointment(w, r)
+
+	raw, err := ioutil.ReadAll(w.Result().Body)
+	assert.NoError(t, err)
+	fmt.Println(string(raw))
+
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+}
+
+func TestCreateAppointment_Forbidden(t *testing.T) {
+	ctx := context.Background()
+	businessDao := createTestBusinessDao(ctx, t)
+	clientDao := createTestClientDao(ctx, t)
+	dao := createTestAppointmentDao(ctx, t)
+	server := NewServer(businessDao, clientDao, dao, nil, nil)
+
+	business, err := businessDao.Create(ctx, businessdao.CreateInput{
+		Name:   "bar",
+		UserID: "owner",
+	})
+	assert.NoError(t, err)
+	client, err := clientDao.Create(ctx, clientdao.CreateInput{
+		FirstName: "foo",
+		LastName:  "baz",
+		UserID:    "client",
+	})
+	assert.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	body := bytes.NewReader([]byte(fmt.Sprintf(`{
+		"clientId":"%v",
+		"businessId":"%v",
+		"date":"2032-12-02T15:04:05+07:00",
+		"endDate":"2032-12-02T16:04:05+07:00"
+	}`, client.ID, business.ID)))
+	r := httptest.NewRequest(http.MethodPost, "/", body)
+	r = r.WithContext(ContextWithUser(ctx, User{ID: "stranger"}))
+	server.CreateAppointment(w, r)
+
+	assert.Equal(t, http.StatusForbidden, w.Result().StatusCode)
+}
+
+func TestCreateAppointment_BusinessNotFound(t *testing.T) {
+	ctx := context.Background()
+	businessDao := createTestBusinessDao(ctx, t)
+	dao := createTestAppointmentDao(ctx, t)
+	server := NewServer(businessDao, nil, dao, nil, nil)
+
+	w := httptest.NewRecorder()
+	body := bytes.NewReader([]byte(`{
+		"clientId":"foo",
+		"businessId":"notexists",
+		"date":"2032-12-02T15:04:05+07:00",
+		"endDate":"2032-12-02T16:04:05+07:00"
+	}`))
+	r := httptest.NewRequest(http.MethodPost, "/", body)
+	r = r.WithContext(ContextWithUser(ctx, User{}))
+	server.CreateAppointment(w, r)
+
+	assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+}
+
+func TestCreateAppointment_OutsideOpeningHours(t *testing.T) {
+	ctx := context.Background()
+	businessDao := createTestBusinessDao(ctx, t)
+	clientDao := createTestClientDao(ctx, t)
+	dao := createTestAppointmentDao(ctx, t)
+	server := NewServer(businessDao, clientDao, dao, nil, nil)
+
+	client, err := clientDao.Create(ctx, clientdao.CreateInput{
+		FirstName: "foo",
+	})
+	assert.NoError(t, err)
+
+	business, err := businessDao.Create(ctx, businessdao.CreateInput{
+		Name:   "bar",
+		UserID: "owner",
+		OpeningHours: &businessdao.OpeningHours{
+			TimeZone: "UTC",
+			Weekly: map[string][]businessdao.TimeRange{
+				"thursday": {{Start: "09:00", End: "17:00"}},
+			},
+		},
+	})
+	assert.NoError(t, err)
 
-	if cleanUp {
-		t.Cleanup(func() {
-			deleteCollection(ctx, t, client, appointmentCollection)
+	// 2032-12-02 is a thursday
+	tests := map[string]struct {
+		date, endDate string
+		status        int
+	}{
+		"inside":     {"2032-12-02T10:00:00Z", "2032-12-02T11:00:00Z", http.StatusOK},
+		"too late":   {"2032-12-02T16:30:00Z", "2032-12-02T17:30:00Z", http.StatusBadRequest},
+		"closed day": {"2032-12-03T10:00:00Z", "2032-12-03T11:00:00Z", http.StatusBadRequest},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			body := bytes.NewReader([]byte(fmt.Sprintf(`{
+				"clientId":"%v",
+				"businessId":"%v",
+				"date":"%v",
+				"endDate":"%v"
+			}`, client.ID, business.ID, tt.date, tt.endDate)))
+			r := httptest.NewRequest(http.MethodPost, "/", body)
+			r = r.WithContext(ContextWithUser(ctx, User{ID: "owner"}))
+			server.CreateAppointment(w, r)
+
+			assert.Equal(t, tt.status, w.Result().StatusCode)
 		})
 	}
+}
+
+func TestCreateAppointment_ClientNotFound(t *testing.T) {
+	ctx := context.Background()
+	businessDao := createTestBusinessDao(ctx, t)
+	clientDao := createTestClientDao(ctx, t)
+	dao := createTestAppointmentDao(ctx, t)
+	server := NewServer(businessDao, clientDao, dao, nil, nil)
+
+	business, err := businessDao.Create(ctx, businessdao.CreateInput{
+		Name:   "bar",
+		UserID: "owner",
+	})
+	assert.NoError(t, err)
+
+	// the business owner books for a client that does not exist
+	w := httptest.NewRecorder()
+	body := bytes.NewReader([]byte(fmt.Sprintf(`{
+		"clientId":"notexists",
+		"businessId":"%v",
+		"date":"2032-12-02T15:04:05+07:00",
+		"endDate":"2032-12-02T16:04:05+07:00"
+	}`, business.ID)))
+	r := httptest.NewRequest(http.MethodPost, "/", body)
+	r = r.WithContext(ContextWithUser(ctx, User{ID: "owner"}))
+	server.CreateAppointment(w, r)
 
-	return dao
+	assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+	appointments, _, err := dao.GetAllAppointments(ctx, appointmentdao.GetAllAppointmentsInput{BusinessID: business.ID})
+	assert.NoError(t, err)
+	assert.Empty(t, appointments)
 }
 
-func TestCreateAppointment(t *testing.T) {
+func TestCreateAppointment_Conflict(t *testing.T) {
+	ctx := context.Background()
+	businessDao := createTestBusinessDao(ctx, t)
+	clientDao := createTestClientDao(ctx, t)
+	dao := createTestAppointmentDao(ctx, t)
+	server := NewServer(businessDao, clientDao, dao, nil, nil)
+
+	business, err := businessDao.Create(ctx, businessdao.CreateInput{
+		Name:   "bar",
+		UserID: "owner",
+	})
+	assert.NoError(t, err)
+	client, err := clientDao.Create(ctx, clientdao.CreateInput{
+		FirstName: "baz",
+	})
+	assert.NoError(t, err)
+
+	inputTime := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	_, err = dao.Create(ctx, appointmentdao.CreateInput{
+		ClientID:   "foo",
+		BusinessID: business.ID,
+		Date:       inputTime,
+		EndDate:    inputTime.Add(time.Hour),
+	})
+	assert.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	body := bytes.NewReader([]byte(fmt.Sprintf(`{
+		"clientId":"%v",
+		"businessId":"%v",
+		"date":"%v",
+		"endDate":"%v"
+	}`, client.ID, business.ID, inputTime.Add(30*time.Minute).Format(time.RFC3339), inputTime.Add(90*time.Minute).Format(time.RFC3339))))
+	r := httptest.NewRequest(http.MethodPost, "/", body)
+	r = r.WithContext(ContextWithUser(ctx, User{ID: "owner"}))
+	server.CreateAppointment(w, r)
+
+	assert.Equal(t, http.StatusConflict, w.Result().StatusCode)
+
+	raw, err := ioutil.ReadAll(w.Result().Body)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"error":"appointment conflicts with an existing appointment"}`, string(raw))
+}
+
+func TestCreateAppointment_EndBeforeStart(t *testing.T) {
 	ctx := context.Background()
 	dao := createTestAppointmentDao(ctx, t)
 	server := NewServer(nil, nil, dao, nil, nil)
@@ -51,17 +248,41 @@ func TestCreateAppointment(t *testing.T) {
 	body := bytes.NewReader([]byte(`{
 		"clientId":"foo",
 		"businessId":"bar",
-		"date":"2022-12-02T15:04:05+07:00"
+		"date":"2032-12-02T15:04:05+07:00",
+		"endDate":"2032-12-02T14:04:05+07:00"
 	}`))
 	r := httptest.NewRequest(http.MethodPost, "/", body)
 	r = r.WithContext(ContextWithUser(ctx, User{}))
 	server.CreateAppointment(w, r)
 
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+
 	raw, err := ioutil.ReadAll(w.Result().Body)
 	assert.NoError(t, err)
-	fmt.Println(string(raw))
+	assert.Equal(t, `{"error":"endDate must be after date"}`, string(raw))
+}
 
-	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+func TestCreateAppointment_TooLong(t *testing.T) {
+	ctx := context.Background()
+	dao := createTestAppointmentDao(ctx, t)
+	server := NewServer(nil, nil, dao, nil, nil)
+
+	w := httptest.NewRecorder()
+	body := bytes.NewReader([]byte(`{
+		"clientId":"foo",
+		"businessId":"bar",
+		"date":"2032-12-02T15:04:05+07:00",
+		"endDate":"2032-12-04T15:04:05+07:00"
+	}`))
+	r := httptest.NewRequest(http.MethodPost, "/", body)
+	r = r.WithContext(ContextWithUser(ctx, User{}))
+	server.CreateAppointment(w, r)
+
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+
+	raw, err := ioutil.ReadAll(w.Result().Body)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"error":"appointment cannot last more than 24h0m0s"}`, string(raw))
 }
 
 func TestGetAppointment(t *testing.T) {
@@ -122,11 +343,13 @@ func TestGetAllAppointments(t *testing.T) {
 	server.GetAllAppointments(w, r)
 	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
 
-	var response []appointmentdao.Appointment
+	var response struct {
+		Items []appointmentdao.Appointment `json:"items"`
+	}
 	err = json.NewDecoder(w.Result().Body).Decode(&response)
 	assert.NoError(t, err)
 
-	assert.Equal(t, 2, len(response))
+	assert.Equal(t, 2, len(response.Items))
 }
 
 func TestGetAllAppointments_ByClientId(t *testing.T) {
@@ -145,13 +368,13 @@ func TestGetAllAppointments_ByClientId(t *testing.T) {
 	_, err = dao.Create(ctx, appointmentdao.CreateInput{
 		ClientID:   "foo",
 		BusinessID: "baz",
-		Date:       inputTime,
+		Date:       inputTime.Add(1 * time.Hour),
 	})
 	assert.NoError(t, err)
 	_, err = dao.Create(ctx, appointmentdao.CreateInput{
 		ClientID:   "duck",
 		BusinessID: "bar",
-		Date:       inputTime,
+		Date:       inputTime.Add(2 * time.Hour),
 	})
 	assert.NoError(t, err)
 
@@ -162,11 +385,13 @@ func TestGetAllAppointments_ByClientId(t *testing.T) {
 	server.GetAllAppointments(w, r)
 	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
 
-	var response []appointmentdao.Appointment
+	var response struct {
+		Items []appointmentdao.Appointment `json:"items"`
+	}
 	err = json.NewDecoder(w.Result().Body).Decode(&response)
 	assert.NoError(t, err)
 
-	assert.Equal(t, 2, len(response))
+	assert.Equal(t, 2, len(response.Items))
 }
 
 func TestGetAllAppointments_ByClientId_ByBusinessId(t *testing.T) {
@@ -185,13 +410,13 @@ func TestGetAllAppointments_ByClientId_ByBusinessId(t *testing.T) {
 	_, err = dao.Create(ctx, appointmentdao.CreateInput{
 		ClientID:   "foo",
 		BusinessID: "bar",
-		Date:       inputTime,
+		Date:       inputTime.Add(1 * time.Hour),
 	})
 	assert.NoError(t, err)
 	_, err = dao.Create(ctx, appointmentdao.CreateInput{
 		ClientID:   "duck",
 		BusinessID: "bar",
-		Date:       inputTime,
+		Date:       inputTime.Add(2 * time.Hour),
 	})
 	assert.NoError(t, err)
 
@@ -202,9 +427,249 @@ func TestGetAllAppointments_ByClientId_ByBusinessId(t *testing.T) {
 	server.GetAllAppointments(w, r)
 	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
 
-	var response []appointmentdao.Appointment
+	var response struct {
+		Items []appointmentdao.Appointment `json:"items"`
+	}
 	err = json.NewDecoder(w.Result().Body).Decode(&response)
 	assert.NoError(t, err)
 
-	assert.Equal(t, 2, len(response))
+	assert.Equal(t, 2, len(response.Items))
+}
+
+func TestChangeAppointmentStatus(t *testing.T) {
+	ctx := context.Background()
+	businessDao := createTestBusinessDao(ctx, t)
+	clientDao := createTestClientDao(ctx, t)
+	dao := createTestAppointmentDao(ctx, t)
+	server := NewServer(businessDao, clientDao, dao, nil, nil)
+
+	business, err := businessDao.Create(ctx, businessdao.CreateInput{
+		Name:   "bar",
+		UserID: "owner",
+	})
+	assert.NoError(t, err)
+	client, err := clientDao.Create(ctx, clientdao.CreateInput{
+		FirstName: "foo",
+		LastName:  "baz",
+		UserID:    "client",
+	})
+	assert.NoError(t, err)
+	appointment, err := dao.Create(ctx, appointmentdao.CreateInput{
+		ClientID:   client.ID,
+		BusinessID: business.ID,
+	})
+	assert.NoError(t, err)
+
+	steps := []struct {
+		action string
+		user   string
+		status int
+	}{
+		// strangers cannot touch the appointment at all
+		{"cancel", "stranger", http.StatusForbidden},
+		// clients cannot confirm
+		{"confirm", "client", http.StatusConflict},
+		{"confirm", "owner", http.StatusOK},
+		// clients cannot complete
+		{"complete", "client", http.StatusConflict},
+		{"complete", "owner", http.StatusOK},
+		// completed appointments cannot be cancelled
+		{"cancel", "client", http.StatusConflict},
+	}
+	for _, step := range steps {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/appointments/%v/%v", appointment.ID, step.action), nil)
+		r = r.WithContext(ContextWithUser(ctx, User{ID: step.user}))
+
+		server.ChangeAppointmentStatus(w, r)
+		assert.Equal(t, step.status, w.Result().StatusCode, "%v by %v", step.action, step.user)
+	}
+
+	gotAppointment, err := dao.GetAppointment(ctx, appointment.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, appointmentdao.StatusCompleted, gotAppointment.Status)
+	assert.Len(t, gotAppointment.StatusHistory, 2)
+}
+
+func TestChangeAppointmentStatus_NotFound(t *testing.T) {
+	ctx := context.Background()
+	dao := createTestAppointmentDao(ctx, t)
+	server := NewServer(nil, nil, dao, nil, nil)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/appointments/notexists/cancel", nil)
+	r = r.WithContext(ContextWithUser(ctx, User{ID: "client"}))
+
+	server.ChangeAppointmentStatus(w, r)
+	assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+}
+
+func TestRescheduleAppointment(t *testing.T) {
+	ctx := context.Background()
+	businessDao := createTestBusinessDao(ctx, t)
+	clientDao := createTestClientDao(ctx, t)
+	dao := createTestAppointmentDao(ctx, t)
+	server := NewServer(businessDao, clientDao, dao, nil, nil)
+
+	business, err := businessDao.Create(ctx, businessdao.CreateInput{
+		Name:   "bar",
+		UserID: "owner",
+	})
+	assert.NoError(t, err)
+	client, err := clientDao.Create(ctx, clientdao.CreateInput{
+		FirstName: "foo",
+		LastName:  "baz",
+		UserID:    "client",
+	})
+	assert.NoError(t, err)
+
+	inputTime := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	appointment, err := dao.Create(ctx, appointmentdao.CreateInput{
+		ClientID:   client.ID,
+		BusinessID: business.ID,
+		Date:       inputTime,
+		EndDate:    inputTime.Add(time.Hour),
+	})
+	assert.NoError(t, err)
+	_, err = dao.Create(ctx, appointmentdao.CreateInput{
+		ClientID:   "baz",
+		BusinessID: business.ID,
+		Date:       inputTime.Add(2 * time.Hour),
+		EndDate:    inputTime.Add(3 * time.Hour),
+	})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		user   string
+		offset time.Duration
+		status int
+	}{
+		{"not a party", "stranger", 4 * time.Hour, http.StatusForbidden},
+		{"overlaps other appointment", "client", 150 * time.Minute, http.StatusConflict},
+		{"free slot", "client", 4 * time.Hour, http.StatusOK},
+		{"in the past", "owner", -48 * time.Hour, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			body := bytes.NewReader([]byte(fmt.Sprintf(`{
+				"date":"%v",
+				"endDate":"%v"
+			}`, inputTime.Add(tt.offset).Format(time.RFC3339), inputTime.Add(tt.offset+time.Hour).Format(time.RFC3339))))
+			r := httptest.NewRequest(http.MethodPatch, "/appointments/"+appointment.ID, body)
+			r = r.WithContext(ContextWithUser(ctx, User{ID: tt.user}))
+
+			server.RescheduleAppointment(w, r)
+			assert.Equal(t, tt.status, w.Result().StatusCode)
+		})
+	}
+
+	gotAppointment, err := dao.GetAppointment(ctx, appointment.ID)
+	assert.NoError(t, err)
+	assert.True(t, inputTime.Add(4*time.Hour).Equal(gotAppointment.Date))
+	assert.Len(t, gotAppointment.Reschedules, 1)
+}
+
+func TestDeleteAppointment(t *testing.T) {
+	ctx := context.Background()
+	businessDao := createTestBusinessDao(ctx, t)
+	clientDao := createTestClientDao(ctx, t)
+	dao := createTestAppointmentDao(ctx, t)
+	server := NewServer(businessDao, clientDao, dao, nil, nil)
+
+	business, err := businessDao.Create(ctx, businessdao.CreateInput{
+		Name:   "bar",
+		UserID: "owner",
+	})
+	assert.NoError(t, err)
+	client, err := clientDao.Create(ctx, clientdao.CreateInput{
+		FirstName: "foo",
+		LastName:  "baz",
+		UserID:    "client",
+	})
+	assert.NoError(t, err)
+
+	baseTime := time.Date(2032, 12, 2, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		user   string
+		status int
+	}{
+		{"stranger", http.StatusForbidden},
+		{"owner", http.StatusOK},
+		{"client", http.StatusOK},
+	}
+	for i, tt := range tests {
+		t.Run(tt.user, func(t *testing.T) {
+			appointment, err := dao.Create(ctx, appointmentdao.CreateInput{
+				ClientID:   client.ID,
+				BusinessID: business.ID,
+				Date:       baseTime.Add(time.Duration(i) * time.Hour),
+				EndDate:    baseTime.Add(time.Duration(i+1) * time.Hour),
+			})
+			assert.NoError(t, err)
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodDelete, "/appointments/"+appointment.ID, nil)
+			r = r.WithContext(ContextWithUser(ctx, User{ID: tt.user}))
+
+			server.DeleteAppointment(w, r)
+			assert.Equal(t, tt.status, w.Result().StatusCode)
+		})
+	}
+}
+
+func TestGetAllAppointments_Filters(t *testing.T) {
+	ctx := context.Background()
+	dao := createTestAppointmentDao(ctx, t)
+	server := NewServer(nil, nil, dao, nil, nil)
+
+	baseTime := time.Date(2032, 12, 2, 9, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		_, err := dao.Create(ctx, appointmentdao.CreateInput{
+			ClientID:   fmt.Sprintf("client%v", i),
+			BusinessID: "bar",
+			Date:       baseTime.Add(time.Duration(i) * 24 * time.Hour),
+			EndDate:    baseTime.Add(time.Duration(i)*24*time.Hour + time.Hour),
+		})
+		assert.NoError(t, err)
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/appointments?business=bar&from=2032-12-03T00:00:00Z&sort=desc", nil)
+
+	server.GetAllAppointments(w, r)
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+
+	var response struct {
+		Items []appointmentdao.Appointment `json:"items"`
+	}
+	err := json.NewDecoder(w.Result().Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Len(t, response.Items, 2)
+	assert.Equal(t, "client2", response.Items[0].ClientID)
+	assert.Equal(t, "client1", response.Items[1].ClientID)
+}
+
+func TestGetAllAppointments_InvalidFilters(t *testing.T) {
+	ctx := context.Background()
+	dao := createTestAppointmentDao(ctx, t)
+	server := NewServer(nil, nil, dao, nil, nil)
+
+	tests := map[string]string{
+		"bad status":  "status=late",
+		"bad from":    "from=yesterday",
+		"bad to":      "to=2032-12-03",
+		"to before":   "from=2032-12-03T00:00:00Z&to=2032-12-01T00:00:00Z",
+		"bad sorting": "sort=newest",
+	}
+	for name, query := range tests {
+		t.Run(name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/appointments?"+query, nil)
+
+			server.GetAllAppointments(w, r)
+			assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+		})
+	}
 }
@@ -4,15 +4,29 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"net/http"
 	"strings"
+	"time"
 
+	"github.com/devduck123/servizio-be/internal/availability"
 	"github.com/devduck123/servizio-be/internal/businessdao"
+	"github.com/devduck123/servizio-be/internal/images"
+	"github.com/devduck123/servizio-be/internal/logging"
+)
+
+const (
+	// maxAvailabilityRange bounds how far apart from and to can be when
+	// requesting availability, to keep responses small.
+	maxAvailabilityRange = 31 * 24 * time.Hour
+	// minSlotDuration and maxAvailabilitySlots bound the number of slots
+	// a request can make the server build, every slot of the range is
+	// built before the busy ones are left out.
+	minSlotDuration      = 5 * time.Minute
+	maxAvailabilitySlots = 2000
 )
 
 func (s *Server) GetBusiness(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("GetBusiness called on:", r.URL.Path)
+	logging.FromContext(r.Context()).Debug("GetBusiness called", "path", r.URL.Path)
 
 	id := strings.TrimPrefix(r.URL.Path, "/businesses/")
 
@@ -31,16 +45,36 @@ func (s *Server) GetBusiness(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) GetAllBusinesses(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("GetAllBusinesses called on:", r.URL.Path, "on", r.URL.RawQuery)
+	logging.FromContext(r.Context()).Debug("GetAllBusinesses called", "path", r.URL.Path, "query", r.URL.RawQuery)
 
 	category := r.URL.Query().Get("category")
 	input := businessdao.GetAllBusinessesInput{
 		Category: businessdao.Category(category),
 	}
 
-	allBusinesses, err := s.businessDao.GetAllBusinesses(r.Context(), input)
+	if owner := r.URL.Query().Get("owner"); owner != "" {
+		if owner != "me" {
+			writeErrorJSON(w, http.StatusBadRequest, errors.New("owner can only be me"))
+			return
+		}
+		user, err := UserFromContext(r.Context())
+		if err != nil {
+			writeErrorJSON(w, http.StatusUnauthorized, err)
+			return
+		}
+		input.UserID = user.ID
+	}
+
+	page, err := pageFromRequest(r)
 	if err != nil {
-		writeErrorJSON(w, http.StatusInternalServerError, err)
+		writeErrorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	input.Page = page
+
+	allBusinesses, next, err := s.businessDao.GetAllBusinesses(r.Context(), input)
+	if err != nil {
+		writeListError(w, err)
 		return
 	}
 	if !input.Category.IsValid() && input.Category != "" {
@@ -48,16 +82,115 @@ func (s *Server) GetAllBusinesses(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	writeJSON(w, http.StatusOK, allBusinesses)
+	writeJSON(w, http.StatusOK, listResponse{
+		Items:      allBusinesses,
+		NextCursor: next,
+	})
+}
+
+func (s *Server) GetBusinessAvailability(w http.ResponseWriter, r *http.Request) {
+	logging.FromContext(r.Context()).Debug("GetBusinessAvailability called", "path", r.URL.Path, "query", r.URL.RawQuery)
+
+	ctx := r.Context()
+
+	trimmedURL := strings.TrimSuffix(r.URL.Path, "/")
+	id := strings.TrimSuffix(strings.TrimPrefix(trimmedURL, "/businesses/"), "/availability")
+
+	query := r.URL.Query()
+	from, err := time.Parse(time.RFC3339, query.Get("from"))
+	if err != nil {
+		writeErrorJSON(w, http.StatusBadRequest, errors.New("from must be an RFC 3339 timestamp"))
+		return
+	}
+	to, err := time.Parse(time.RFC3339, query.Get("to"))
+	if err != nil {
+		writeErrorJSON(w, http.StatusBadRequest, errors.New("to must be an RFC 3339 timestamp"))
+		return
+	}
+	duration, err := time.ParseDuration(query.Get("duration"))
+	if err != nil || duration <= 0 {
+		writeErrorJSON(w, http.StatusBadRequest, errors.New("duration must be a positive duration such as 30m"))
+		return
+	}
+	if duration < minSlotDuration {
+		writeErrorJSON(w, http.StatusBadRequest, fmt.Errorf("duration must be at least %v", minSlotDuration))
+		return
+	}
+	if !to.After(from) {
+		writeErrorJSON(w, http.StatusBadRequest, errors.New("to must be after from"))
+		return
+	}
+	if to.Sub(from) > maxAvailabilityRange {
+		writeErrorJSON(w, http.StatusBadRequest, fmt.Errorf("range cannot exceed %v", maxAvailabilityRange))
+		return
+	}
+	if to.Sub(from)/duration > maxAvailabilitySlots {
+		writeErrorJSON(w, http.StatusBadRequest, fmt.Errorf("range cannot hold more than %v slots, use a longer duration or a shorter range", maxAvailabilitySlots))
+		return
+	}
+
+	business, err := s.businessDao.GetBusiness(ctx, id)
+	if err != nil {
+		if errors.Is(err, businessdao.ErrBusinessNotFound) {
+			writeErrorJSON(w, http.StatusNotFound, err)
+			return
+		}
+
+		writeErrorJSON(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	open := []availability.Interval{{Start: from, End: to}}
+	if business.OpeningHours != nil {
+		open, err = availability.OpenIntervals(*business.OpeningHours, from, to)
+		if err != nil {
+			writeErrorJSON(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	appointments, err := s.appointmentDao.GetBusinessAppointmentsBetween(ctx, id, from, to)
+	if err != nil {
+		writeErrorJSON(w, http.StatusInternalServerError, err)
+		return
+	}
+	busy := make([]availability.Interval, 0, len(appointments))
+	for _, appointment := range appointments {
+		if !appointment.Status.OccupiesSlot() {
+			continue
+		}
+		end := appointment.EndDate
+		if end.IsZero() {
+			// appointments booked before end dates existed block the slots
+			// holding their start, as in appointmentdao.Appointment.Overlaps
+			end = appointment.Date.Add(duration)
+		}
+		busy = append(busy, availability.Interval{
+			Start: appointment.Date,
+			End:   end,
+		})
+	}
+
+	now := time.Now()
+	slots := []availability.Interval{}
+	for _, slot := range availability.FreeSlots(open, busy, duration) {
+		if slot.Start.Before(now) {
+			continue
+		}
+		slots = append(slots, slot)
+	}
+
+	writeJSON(w, http.StatusOK, slots)
 }
 
 type BusinessCreateInput struct {
-	Name     string               `json:"name"`
-	Category businessdao.Category `json:"category"`
+	Name         string                    `json:"name"`
+	Category     businessdao.Category      `json:"category"`
+	OpeningHours *businessdao.OpeningHours `json:"openingHours"`
 }
 
 func (s *Server) CreateBusiness(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("CreateBusiness called on :", r.URL.Path)
+	logging.FromContext(r.Context()).Debug("CreateBusiness called", "path", r.URL.Path)
 
 	user, err := UserFromContext(r.Context())
 	if err != nil {
@@ -72,7 +205,7 @@ func (s *Server) CreateBusiness(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	fmt.Printf("%+v\n", businessCreateInput)
+	logging.FromContext(r.Context()).Debug("decoded input", "input", businessCreateInput)
 
 	if strings.TrimSpace(businessCreateInput.Name) == "" {
 		writeErrorJSON(w, http.StatusBadRequest, errors.New("name cannot be empty"))
@@ -83,11 +216,18 @@ func (s *Server) CreateBusiness(w http.ResponseWriter, r *http.Request) {
 		writeErrorJSON(w, http.StatusBadRequest, errors.New("invalid category"))
 		return
 	}
+	if businessCreateInput.OpeningHours != nil {
+		if err := businessCreateInput.OpeningHours.Validate(); err != nil {
+			writeErrorJSON(w, http.StatusBadRequest, err)
+			return
+		}
+	}
 
 	businessToCreateInput := businessdao.CreateInput{
-		Name:     businessCreateInput.Name,
-		Category: businessCreateInput.Category,
-		UserID:   user.ID,
+		Name:         businessCreateInput.Name,
+		Category:     businessCreateInput.Category,
+		UserID:       user.ID,
+		OpeningHours: businessCreateInput.OpeningHours,
 	}
 	business, err := s.businessDao.Create(r.Context(), businessToCreateInput)
 	if err != nil {
@@ -98,11 +238,108 @@ func (s *Server) CreateBusiness(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, business)
 }
 
+// BusinessUpdateInput is a partial update, fields left out are not changed.
+type BusinessUpdateInput struct {
+	Name         *string                   `json:"name"`
+	Category     *businessdao.Category     `json:"category"`
+	OpeningHours *businessdao.OpeningHours `json:"openingHours"`
+}
+
+func (s *Server) UpdateBusiness(w http.ResponseWriter, r *http.Request) {
+	logging.FromContext(r.Context()).Debug("UpdateBusiness called", "path", r.URL.Path)
+
+	ctx := r.Context()
+
+	user, err := UserFromContext(ctx)
+	if err != nil {
+		writeErrorJSON(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	var businessUpdateInput BusinessUpdateInput
+	err = json.NewDecoder(r.Body).Decode(&businessUpdateInput)
+	if err != nil {
+		writeErrorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if businessUpdateInput.Name != nil && strings.TrimSpace(*businessUpdateInput.Name) == "" {
+		writeErrorJSON(w, http.StatusBadRequest, errors.New("name cannot be empty"))
+		return
+	}
+	if businessUpdateInput.Category != nil && !businessUpdateInput.Category.IsValid() {
+		writeErrorJSON(w, http.StatusBadRequest, errors.New("invalid category"))
+		return
+	}
+	if businessUpdateInput.OpeningHours != nil {
+		if err := businessUpdateInput.OpeningHours.Validate(); err != nil {
+			writeErrorJSON(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/businesses/"), "/")
+	business, err := s.businessDao.GetBusiness(ctx, id)
+	if err != nil {
+		if errors.Is(err, businessdao.ErrBusinessNotFound) {
+			writeErrorJSON(w, http.StatusNotFound, err)
+			return
+		}
+
+		writeErrorJSON(w, http.StatusInternalServerError, err)
+		return
+	}
+	if !owns(user, business.UserID) {
+		writeErrorJSON(w, http.StatusForbidden, errForbidden)
+		return
+	}
+
+	business, err = s.businessDao.Update(ctx, id, businessdao.UpdateInput{
+		Name:         businessUpdateInput.Name,
+		Category:     businessUpdateInput.Category,
+		OpeningHours: businessUpdateInput.OpeningHours,
+	})
+	if err != nil {
+		if errors.Is(err, businessdao.ErrBusinessNotFound) {
+			writeErrorJSON(w, http.StatusNotFound, err)
+			return
+		}
+
+		writeErrorJSON(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, business)
+}
+
 func (s *Server) DeleteBusiness(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("DeleteBusiness called on:", r.URL.Path)
+	logging.FromContext(r.Context()).Debug("DeleteBusiness called", "path", r.URL.Path)
+
+	ctx := r.Context()
+
+	user, err := UserFromContext(ctx)
+	if err != nil {
+		writeErrorJSON(w, http.StatusUnauthorized, err)
+		return
+	}
 
 	id := strings.TrimPrefix(r.URL.Path, "/businesses/")
-	err := s.businessDao.Delete(r.Context(), id)
+	business, err := s.businessDao.GetBusiness(ctx, id)
+	if err != nil {
+		if errors.Is(err, businessdao.ErrBusinessNotFound) {
+			writeErrorJSON(w, http.StatusNotFound, err)
+			return
+		}
+
+		writeErrorJSON(w, http.StatusInternalServerError, err)
+		return
+	}
+	if !owns(user, business.UserID) {
+		writeErrorJSON(w, http.StatusForbidden, errForbidden)
+		return
+	}
+
+	err = s.businessDao.Delete(ctx, id)
 	if err != nil {
 		if errors.Is(err, businessdao.ErrBusinessNotFound) {
 			writeErrorJSON(w, http.StatusNotFound, err)
@@ -116,18 +353,22 @@ func (s *Server) DeleteBusiness(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, "successful deletion")
 }
 
-// TODO: file size limit, file type limit, consider form api
-// TODO: review imageURL
+// TODO: consider form api
 func (s *Server) UploadImageBusiness(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("UploadImage called on:", r.URL.Path)
+	logging.FromContext(r.Context()).Debug("UploadImageBusiness called", "path", r.URL.Path)
 
 	ctx := r.Context()
 
+	user, err := UserFromContext(ctx)
+	if err != nil {
+		writeErrorJSON(w, http.StatusUnauthorized, err)
+		return
+	}
+
 	trimmedURL := strings.TrimSuffix(r.URL.Path, "/")
 	id := strings.TrimSuffix(strings.TrimPrefix(trimmedURL, "/businesses/"), "/images")
 
-	fmt.Println("id:", id)
-	_, err := s.businessDao.GetBusiness(ctx, id)
+	business, err := s.businessDao.GetBusiness(ctx, id)
 	if err != nil {
 		if err == businessdao.ErrBusinessNotFound {
 			writeErrorJSON(w, http.StatusNotFound, err)
@@ -137,29 +378,151 @@ func (s *Server) UploadImageBusiness(w http.ResponseWriter, r *http.Request) {
 		writeErrorJSON(w, http.StatusInternalServerError, err)
 		return
 	}
+	if !owns(user, business.UserID) {
+		writeErrorJSON(w, http.StatusForbidden, errForbidden)
+		return
+	}
 
-	raw, err := ioutil.ReadAll(r.Body)
+	upload, err := s.readImage(w, r)
 	if err != nil {
-		writeErrorJSON(w, http.StatusBadRequest, err)
+		s.writeImageError(w, err)
+		return
+	}
+
+	image, err := s.imageManager.UploadImage(ctx, id, upload)
+	if err != nil {
+		writeErrorJSON(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if err := s.businessDao.AppendImage(ctx, id, image.Ref()); err != nil {
+		writeErrorJSON(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, "success")
+}
+
+func (s *Server) DeleteImageBusiness(w http.ResponseWriter, r *http.Request) {
+	logging.FromContext(r.Context()).Debug("DeleteImageBusiness called", "path", r.URL.Path)
+
+	ctx := r.Context()
+
+	user, err := UserFromContext(ctx)
+	if err != nil {
+		writeErrorJSON(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	id, name, ok := imagePath(r.URL.Path, "/businesses/")
+	if !ok {
+		writeErrorJSON(w, http.StatusNotFound, fmt.Errorf("%v not found", r.URL.Path))
 		return
 	}
 
-	if len(raw) == 0 {
-		writeErrorJSON(w, http.StatusBadRequest, errors.New("no image provided"))
+	business, err := s.businessDao.GetBusiness(ctx, id)
+	if err != nil {
+		if errors.Is(err, businessdao.ErrBusinessNotFound) {
+			writeErrorJSON(w, http.StatusNotFound, err)
+			return
+		}
+
+		writeErrorJSON(w, http.StatusInternalServerError, err)
+		return
+	}
+	if !owns(user, business.UserID) {
+		writeErrorJSON(w, http.StatusForbidden, errForbidden)
 		return
 	}
 
-	image, err := s.imageManager.UploadImage(ctx, id, raw)
+	key := imageKey(id, name)
+	if !hasImage(business.Images, key) {
+		writeErrorJSON(w, http.StatusNotFound, images.ErrImageNotFound)
+		return
+	}
+
+	// the entry goes first, an object left behind is only wasted space but
+	// an entry without its object is a broken image
+	err = s.businessDao.RemoveImage(ctx, id, key)
 	if err != nil {
+		if errors.Is(err, businessdao.ErrBusinessNotFound) || errors.Is(err, images.ErrImageNotFound) {
+			writeErrorJSON(w, http.StatusNotFound, err)
+			return
+		}
+
 		writeErrorJSON(w, http.StatusInternalServerError, err)
 		return
 	}
+	s.deleteStoredImage(ctx, key)
+
+	writeJSON(w, http.StatusOK, "successful deletion")
+}
+
+// ImageOrderInput lists every image of a business by the last part of its
+// path, in the order to show them, and the one to use as the cover.
+type ImageOrderInput struct {
+	Order []string `json:"order"`
+	// Cover is optional, leaving it out unsets the cover image
+	Cover string `json:"cover"`
+}
+
+func (s *Server) OrderImagesBusiness(w http.ResponseWriter, r *http.Request) {
+	logging.FromContext(r.Context()).Debug("OrderImagesBusiness called", "path", r.URL.Path)
+
+	ctx := r.Context()
+
+	user, err := UserFromContext(ctx)
+	if err != nil {
+		writeErrorJSON(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	var imageOrderInput ImageOrderInput
+	err = json.NewDecoder(r.Body).Decode(&imageOrderInput)
+	if err != nil {
+		writeErrorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+
+	trimmedURL := strings.TrimSuffix(r.URL.Path, "/")
+	id := strings.TrimSuffix(strings.TrimPrefix(trimmedURL, "/businesses/"), "/images/order")
+
+	business, err := s.businessDao.GetBusiness(ctx, id)
+	if err != nil {
+		if errors.Is(err, businessdao.ErrBusinessNotFound) {
+			writeErrorJSON(w, http.StatusNotFound, err)
+			return
+		}
 
-	imageURL := fmt.Sprintf("servizio-be.appspot.com/%v", image.Key)
-	if err := s.businessDao.AppendImage(ctx, id, imageURL); err != nil {
 		writeErrorJSON(w, http.StatusInternalServerError, err)
 		return
 	}
+	if !owns(user, business.UserID) {
+		writeErrorJSON(w, http.StatusForbidden, errForbidden)
+		return
+	}
 
-	writeJSON(w, http.StatusOK, "success")
+	keys := make([]string, 0, len(imageOrderInput.Order))
+	for _, name := range imageOrderInput.Order {
+		keys = append(keys, imageKey(id, name))
+	}
+	var cover string
+	if imageOrderInput.Cover != "" {
+		cover = imageKey(id, imageOrderInput.Cover)
+	}
+
+	business, err = s.businessDao.OrderImages(ctx, id, keys, cover)
+	if err != nil {
+		switch {
+		case errors.Is(err, businessdao.ErrBusinessNotFound):
+			writeErrorJSON(w, http.StatusNotFound, err)
+		case errors.Is(err, images.ErrInvalidOrder), errors.Is(err, businessdao.ErrInvalidCover):
+			writeErrorJSON(w, http.StatusBadRequest, err)
+		default:
+			writeErrorJSON(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, business)
 }
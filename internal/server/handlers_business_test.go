@@ -10,36 +10,18 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
-	"cloud.google.com/go/firestore"
+	"github.com/devduck123/servizio-be/internal/appointmentdao"
+	"github.com/devduck123/servizio-be/internal/availability"
 	"github.com/devduck123/servizio-be/internal/businessdao"
-	"github.com/google/uuid"
 	"github.com/tj/assert"
 )
 
-func createTestBusinessDao(ctx context.Context, t *testing.T, keepCollection ...bool) *businessdao.Dao {
+func createTestBusinessDao(ctx context.Context, t *testing.T) businessdao.Store {
 	t.Helper()
 
-	client, err := firestore.NewClient(ctx, projectID)
-	assert.NoError(t, err)
-	businessCollection := fmt.Sprintf("business-%v", uuid.New())
-	dao := businessdao.NewDao(client, businessCollection)
-
-	cleanUp := true
-	for _, kc := range keepCollection {
-		if kc {
-			cleanUp = false
-			break
-		}
-	}
-
-	if cleanUp {
-		t.Cleanup(func() {
-			deleteCollection(ctx, t, client, businessCollection)
-		})
-	}
-
-	return dao
+	return businessdao.NewMemoryStore()
 }
 
 func TestCreateBusiness_Invalid(t *testing.T) {
@@ -154,11 +136,13 @@ func TestGetAllBusinesses_Valid(t *testing.T) {
 	server.GetAllBusinesses(w, r)
 	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
 
-	var response []businessdao.Business
+	var response struct {
+		Items []businessdao.Business `json:"items"`
+	}
 	err = json.NewDecoder(w.Result().Body).Decode(&response)
 	assert.NoError(t, err)
 
-	assert.Equal(t, 3, len(response))
+	assert.Equal(t, 3, len(response.Items))
 }
 
 func TestGetAllBusinessesByCategory_Valid(t *testing.T) {
@@ -189,11 +173,13 @@ func TestGetAllBusinessesByCategory_Valid(t *testing.T) {
 	server.GetAllBusinesses(w, r)
 	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
 
-	var response []businessdao.Business
+	var response struct {
+		Items []businessdao.Business `json:"items"`
+	}
 	err = json.NewDecoder(w.Result().Body).Decode(&response)
 	assert.NoError(t, err)
 
-	assert.Equal(t, 2, len(response))
+	assert.Equal(t, 2, len(response.Items))
 }
 
 func TestGetAllBusinessesByCategory_Invalid(t *testing.T) {
@@ -228,3 +214,254 @@ func TestGetAllBusinessesByCategory_Invalid(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, `{"error":"invalid category"}`, string(raw))
 }
+
+func TestGetAllBusinesses_OwnerMe(t *testing.T) {
+	ctx := context.Background()
+	dao := createTestBusinessDao(ctx, t)
+	server := NewServer(dao, nil, nil, nil, nil)
+
+	_, err := dao.Create(ctx, businessdao.CreateInput{
+		Name:   "foo",
+		UserID: "owner",
+	})
+	assert.NoError(t, err)
+	_, err = dao.Create(ctx, businessdao.CreateInput{
+		Name:   "bar",
+		UserID: "someone else",
+	})
+	assert.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/businesses?owner=me", nil)
+	r = r.WithContext(ContextWithUser(ctx, User{ID: "owner"}))
+
+	server.GetAllBusinesses(w, r)
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+
+	var response struct {
+		Items []businessdao.Business `json:"items"`
+	}
+	err = json.NewDecoder(w.Result().Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Len(t, response.Items, 1)
+	assert.Equal(t, "foo", response.Items[0].Name)
+}
+
+func TestCreateBusiness_InvalidOpeningHours(t *testing.T) {
+	ctx := context.Background()
+	dao := createTestBusinessDao(ctx, t)
+	server := NewServer(dao, nil, nil, nil, nil)
+
+	w := httptest.NewRecorder()
+	body := bytes.NewReader([]byte(`{
+		"name": "test",
+		"category": "pets",
+		"openingHours": {
+			"timeZone": "Europe/Rome",
+			"weekly": {"monday": [{"start": "17:00", "end": "09:00"}]}
+		}
+	}`))
+	r := httptest.NewRequest(http.MethodPost, "/", body)
+	r = r.WithContext(ContextWithUser(ctx, User{}))
+	server.CreateBusiness(w, r)
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+}
+
+func TestGetBusinessAvailability(t *testing.T) {
+	ctx := context.Background()
+	dao := createTestBusinessDao(ctx, t)
+	appointmentDao := createTestAppointmentDao(ctx, t)
+	server := NewServer(dao, nil, appointmentDao, nil, nil)
+
+	business, err := dao.Create(ctx, businessdao.CreateInput{
+		Name:     "foo",
+		Category: businessdao.CategoryPets,
+		OpeningHours: &businessdao.OpeningHours{
+			TimeZone: "UTC",
+			Weekly: map[string][]businessdao.TimeRange{
+				"thursday": {{Start: "09:00", End: "12:00"}},
+			},
+		},
+	})
+	assert.NoError(t, err)
+
+	// 2032-12-02 is a thursday
+	booked := time.Date(2032, 12, 2, 10, 0, 0, 0, time.UTC)
+	_, err = appointmentDao.Create(ctx, appointmentdao.CreateInput{
+		ClientID:   "bar",
+		BusinessID: business.ID,
+		Date:       booked,
+		EndDate:    booked.Add(time.Hour),
+	})
+	assert.NoError(t, err)
+
+	availabilityURL := fmt.Sprintf("/businesses/%s/availability?from=2032-12-01T00:00:00Z&to=2032-12-04T00:00:00Z&duration=30m", business.ID)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, availabilityURL, nil)
+
+	server.BusinessRouter(w, r)
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+
+	var response []availability.Interval
+	err = json.NewDecoder(w.Result().Body).Decode(&response)
+	assert.NoError(t, err)
+
+	// 09:00-12:00 minus the 10:00-11:00 booking
+	assert.Len(t, response, 4)
+	assert.Equal(t, 9, response[0].Start.Hour())
+	assert.Equal(t, 9, response[1].Start.Hour())
+	assert.Equal(t, 11, response[2].Start.Hour())
+	assert.Equal(t, 11, response[3].Start.Hour())
+}
+
+func TestGetBusinessAvailability_LegacyAppointment(t *testing.T) {
+	ctx := context.Background()
+	dao := createTestBusinessDao(ctx, t)
+	appointmentDao := createTestAppointmentDao(ctx, t)
+	server := NewServer(dao, nil, appointmentDao, nil, nil)
+
+	business, err := dao.Create(ctx, businessdao.CreateInput{
+		Name:     "foo",
+		Category: businessdao.CategoryPets,
+		OpeningHours: &businessdao.OpeningHours{
+			TimeZone: "UTC",
+			Weekly: map[string][]businessdao.TimeRange{
+				"thursday": {{Start: "09:00", End: "12:00"}},
+			},
+		},
+	})
+	assert.NoError(t, err)
+
+	// booked before end dates existed, it only occupies 10:15
+	booked := time.Date(2032, 12, 2, 10, 15, 0, 0, time.UTC)
+	_, err = appointmentDao.Create(ctx, appointmentdao.CreateInput{
+		ClientID:   "bar",
+		BusinessID: business.ID,
+		Date:       booked,
+	})
+	assert.NoError(t, err)
+
+	availabilityURL := fmt.Sprintf("/businesses/%s/availability?from=2032-12-01T00:00:00Z&to=2032-12-04T00:00:00Z&duration=30m", business.ID)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, availabilityURL, nil)
+
+	server.BusinessRouter(w, r)
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+
+	var response []availability.Interval
+	err = json.NewDecoder(w.Result().Body).Decode(&response)
+	assert.NoError(t, err)
+
+	// 09:00, 09:30, 10:45 and 11:15, no slot holds 10:15
+	assert.Len(t, response, 4)
+	for _, slot := range response {
+		assert.False(t, !booked.Before(slot.Start) && booked.Before(slot.End), slot.Start)
+
+		// every slot offered can be booked
+		_, err := appointmentDao.Create(ctx, appointmentdao.CreateInput{
+			ClientID:   "baz",
+			BusinessID: business.ID,
+			Date:       slot.Start,
+			EndDate:    slot.End,
+		})
+		assert.NoError(t, err, slot.Start)
+	}
+}
+
+func TestGetBusinessAvailability_Invalid(t *testing.T) {
+	ctx := context.Background()
+	dao := createTestBusinessDao(ctx, t)
+	server := NewServer(dao, nil, nil, nil, nil)
+
+	tests := map[string]string{
+		"missing from":  "to=2032-12-04T00:00:00Z&duration=30m",
+		"bad duration":  "from=2032-12-01T00:00:00Z&to=2032-12-04T00:00:00Z&duration=half-an-hour",
+		"to before":     "from=2032-12-04T00:00:00Z&to=2032-12-01T00:00:00Z&duration=30m",
+		"range too big": "from=2032-01-01T00:00:00Z&to=2032-12-01T00:00:00Z&duration=30m",
+		// one nanosecond slots over the allowed range would be 2.7e15 slots
+		"duration too short": "from=2032-12-01T00:00:00Z&to=2033-01-01T00:00:00Z&duration=1ns",
+		"too many slots":     "from=2032-12-01T00:00:00Z&to=2033-01-01T00:00:00Z&duration=5m",
+	}
+	for name, query := range tests {
+		t.Run(name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/businesses/foo/availability?"+query, nil)
+
+			server.GetBusinessAvailability(w, r)
+			assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+		})
+	}
+}
+
+func TestDeleteBusiness(t *testing.T) {
+	ctx := context.Background()
+	dao := createTestBusinessDao(ctx, t)
+	server := NewServer(dao, nil, nil, nil, nil)
+
+	business, err := dao.Create(ctx, businessdao.CreateInput{
+		Name:   "foo",
+		UserID: "owner",
+	})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		user   string
+		status int
+	}{
+		{"not the owner", "stranger", http.StatusForbidden},
+		{"owner", "owner", http.StatusOK},
+		{"not found", "owner", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodDelete, "/businesses/"+business.ID, nil)
+			r = r.WithContext(ContextWithUser(ctx, User{ID: tt.user}))
+
+			server.DeleteBusiness(w, r)
+			assert.Equal(t, tt.status, w.Result().StatusCode)
+		})
+	}
+}
+
+func TestUpdateBusiness(t *testing.T) {
+	ctx := context.Background()
+	dao := createTestBusinessDao(ctx, t)
+	server := NewServer(dao, nil, nil, nil, nil)
+
+	business, err := dao.Create(ctx, businessdao.CreateInput{
+		Name:     "foo",
+		Category: businessdao.CategoryPets,
+		UserID:   "owner",
+	})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		user   string
+		body   string
+		status int
+	}{
+		{"not the owner", "stranger", `{"name":"bar"}`, http.StatusForbidden},
+		{"empty name", "owner", `{"name":" "}`, http.StatusBadRequest},
+		{"invalid category", "owner", `{"category":"pokemon"}`, http.StatusBadRequest},
+		{"name only", "owner", `{"name":"bar"}`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPatch, "/businesses/"+business.ID, bytes.NewReader([]byte(tt.body)))
+			r = r.WithContext(ContextWithUser(ctx, User{ID: tt.user}))
+
+			server.UpdateBusiness(w, r)
+			assert.Equal(t, tt.status, w.Result().StatusCode)
+		})
+	}
+
+	updated, err := dao.GetBusiness(ctx, business.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "bar", updated.Name)
+	assert.Equal(t, businessdao.CategoryPets, updated.Category)
+}
@@ -4,15 +4,16 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"net/http"
 	"strings"
 
 	"github.com/devduck123/servizio-be/internal/clientdao"
+	"github.com/devduck123/servizio-be/internal/images"
+	"github.com/devduck123/servizio-be/internal/logging"
 )
 
 func (s *Server) GetClient(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("GetClient called on:", r.URL.Path)
+	logging.FromContext(r.Context()).Debug("GetClient called", "path", r.URL.Path)
 
 	id := strings.TrimPrefix(r.URL.Path, "/clients/")
 
@@ -30,16 +31,51 @@ func (s *Server) GetClient(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, client)
 }
 
-func (s *Server) GetAllClients(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("GetAllClients called on:", r.URL.Path)
+// GetMyClient returns the client profile of the authenticated user.
+func (s *Server) GetMyClient(w http.ResponseWriter, r *http.Request) {
+	logging.FromContext(r.Context()).Debug("GetMyClient called", "path", r.URL.Path)
 
-	allClients, err := s.clientDao.GetAllClients(r.Context())
+	user, err := UserFromContext(r.Context())
 	if err != nil {
+		writeErrorJSON(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	client, err := s.clientDao.GetClientByUserID(r.Context(), user.ID)
+	if err != nil {
+		if errors.Is(err, clientdao.ErrClientNotFound) {
+			writeErrorJSON(w, http.StatusNotFound, err)
+			return
+		}
+
 		writeErrorJSON(w, http.StatusInternalServerError, err)
 		return
 	}
 
-	writeJSON(w, http.StatusOK, allClients)
+	writeJSON(w, http.StatusOK, client)
+}
+
+func (s *Server) GetAllClients(w http.ResponseWriter, r *http.Request) {
+	logging.FromContext(r.Context()).Debug("GetAllClients called", "path", r.URL.Path)
+
+	page, err := pageFromRequest(r)
+	if err != nil {
+		writeErrorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+
+	allClients, next, err := s.clientDao.GetAllClients(r.Context(), clientdao.GetAllClientsInput{
+		Page: page,
+	})
+	if err != nil {
+		writeListError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, listResponse{
+		Items:      allClients,
+		NextCursor: next,
+	})
 }
 
 type ClientCreateInput struct {
@@ -48,7 +84,7 @@ type ClientCreateInput struct {
 }
 
 func (s *Server) CreateClient(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("CreateClient called on :", r.URL.Path)
+	logging.FromContext(r.Context()).Debug("CreateClient called", "path", r.URL.Path)
 
 	user, err := UserFromContext(r.Context())
 	if err != nil {
@@ -63,7 +99,7 @@ func (s *Server) CreateClient(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	fmt.Printf("%+v\n", clientCreateInput)
+	logging.FromContext(r.Context()).Debug("decoded input", "input", clientCreateInput)
 
 	if strings.TrimSpace(clientCreateInput.FirstName) == "" {
 		writeErrorJSON(w, http.StatusBadRequest, errors.New("first name cannot be empty"))
@@ -81,6 +117,77 @@ func (s *Server) CreateClient(w http.ResponseWriter, r *http.Request) {
 	}
 	client, err := s.clientDao.Create(r.Context(), clientToCreateInput)
 	if err != nil {
+		if errors.Is(err, clientdao.ErrClientExists) {
+			writeErrorJSON(w, http.StatusConflict, err)
+			return
+		}
+
+		writeErrorJSON(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, client)
+}
+
+// ClientUpdateInput is a partial update, fields left out are not changed.
+type ClientUpdateInput struct {
+	FirstName *string `json:"firstname"`
+	LastName  *string `json:"lastname"`
+}
+
+func (s *Server) UpdateClient(w http.ResponseWriter, r *http.Request) {
+	logging.FromContext(r.Context()).Debug("UpdateClient called", "path", r.URL.Path)
+
+	ctx := r.Context()
+
+	user, err := UserFromContext(ctx)
+	if err != nil {
+		writeErrorJSON(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	var clientUpdateInput ClientUpdateInput
+	err = json.NewDecoder(r.Body).Decode(&clientUpdateInput)
+	if err != nil {
+		writeErrorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if clientUpdateInput.FirstName != nil && strings.TrimSpace(*clientUpdateInput.FirstName) == "" {
+		writeErrorJSON(w, http.StatusBadRequest, errors.New("first name cannot be empty"))
+		return
+	}
+	if clientUpdateInput.LastName != nil && strings.TrimSpace(*clientUpdateInput.LastName) == "" {
+		writeErrorJSON(w, http.StatusBadRequest, errors.New("last name cannot be empty"))
+		return
+	}
+
+	id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/clients/"), "/")
+	client, err := s.clientDao.GetClient(ctx, id)
+	if err != nil {
+		if errors.Is(err, clientdao.ErrClientNotFound) {
+			writeErrorJSON(w, http.StatusNotFound, err)
+			return
+		}
+
+		writeErrorJSON(w, http.StatusInternalServerError, err)
+		return
+	}
+	if !owns(user, client.UserID) {
+		writeErrorJSON(w, http.StatusForbidden, errForbidden)
+		return
+	}
+
+	client, err = s.clientDao.Update(ctx, id, clientdao.UpdateInput{
+		FirstName: clientUpdateInput.FirstName,
+		LastName:  clientUpdateInput.LastName,
+	})
+	if err != nil {
+		if errors.Is(err, clientdao.ErrClientNotFound) {
+			writeErrorJSON(w, http.StatusNotFound, err)
+			return
+		}
+
 		writeErrorJSON(w, http.StatusInternalServerError, err)
 		return
 	}
@@ -89,10 +196,33 @@ func (s *Server) CreateClient(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) DeleteClient(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("DeleteClient called on:", r.URL.Path)
+	logging.FromContext(r.Context()).Debug("DeleteClient called", "path", r.URL.Path)
+
+	ctx := r.Context()
+
+	user, err := UserFromContext(ctx)
+	if err != nil {
+		writeErrorJSON(w, http.StatusUnauthorized, err)
+		return
+	}
 
 	id := strings.TrimPrefix(r.URL.Path, "/clients/")
-	err := s.clientDao.Delete(r.Context(), id)
+	client, err := s.clientDao.GetClient(ctx, id)
+	if err != nil {
+		if errors.Is(err, clientdao.ErrClientNotFound) {
+			writeErrorJSON(w, http.StatusNotFound, err)
+			return
+		}
+
+		writeErrorJSON(w, http.StatusInternalServerError, err)
+		return
+	}
+	if !owns(user, client.UserID) {
+		writeErrorJSON(w, http.StatusForbidden, errForbidden)
+		return
+	}
+
+	err = s.clientDao.Delete(ctx, id)
 	if err != nil {
 		if errors.Is(err, clientdao.ErrClientNotFound) {
 			writeErrorJSON(w, http.StatusNotFound, err)
@@ -106,18 +236,22 @@ func (s *Server) DeleteClient(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, "successful deletion")
 }
 
-// TODO: file size limit, file type limit, consider form api
-// TODO: review imageURL
+// TODO: consider form api
 func (s *Server) UploadImageClient(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("UploadImage called on:", r.URL.Path)
+	logging.FromContext(r.Context()).Debug("UploadImageClient called", "path", r.URL.Path)
 
 	ctx := r.Context()
 
+	user, err := UserFromContext(ctx)
+	if err != nil {
+		writeErrorJSON(w, http.StatusUnauthorized, err)
+		return
+	}
+
 	trimmedURL := strings.TrimSuffix(r.URL.Path, "/")
 	id := strings.TrimSuffix(strings.TrimPrefix(trimmedURL, "/clients/"), "/images")
 
-	fmt.Println("id:", id)
-	_, err := s.clientDao.GetClient(ctx, id)
+	client, err := s.clientDao.GetClient(ctx, id)
 	if err != nil {
 		if err == clientdao.ErrClientNotFound {
 			writeErrorJSON(w, http.StatusNotFound, err)
@@ -127,29 +261,82 @@ func (s *Server) UploadImageClient(w http.ResponseWriter, r *http.Request) {
 		writeErrorJSON(w, http.StatusInternalServerError, err)
 		return
 	}
+	if !owns(user, client.UserID) {
+		writeErrorJSON(w, http.StatusForbidden, errForbidden)
+		return
+	}
 
-	raw, err := ioutil.ReadAll(r.Body)
+	upload, err := s.readImage(w, r)
 	if err != nil {
-		writeErrorJSON(w, http.StatusBadRequest, err)
+		s.writeImageError(w, err)
+		return
+	}
+
+	image, err := s.imageManager.UploadImage(ctx, id, upload)
+	if err != nil {
+		writeErrorJSON(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if err := s.clientDao.AppendImage(ctx, id, image.Ref()); err != nil {
+		writeErrorJSON(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, "success")
+}
+
+func (s *Server) DeleteImageClient(w http.ResponseWriter, r *http.Request) {
+	logging.FromContext(r.Context()).Debug("DeleteImageClient called", "path", r.URL.Path)
+
+	ctx := r.Context()
+
+	user, err := UserFromContext(ctx)
+	if err != nil {
+		writeErrorJSON(w, http.StatusUnauthorized, err)
 		return
 	}
 
-	if len(raw) == 0 {
-		writeErrorJSON(w, http.StatusBadRequest, errors.New("no image provided"))
+	id, name, ok := imagePath(r.URL.Path, "/clients/")
+	if !ok {
+		writeErrorJSON(w, http.StatusNotFound, fmt.Errorf("%v not found", r.URL.Path))
 		return
 	}
 
-	image, err := s.imageManager.UploadImage(ctx, id, raw)
+	client, err := s.clientDao.GetClient(ctx, id)
 	if err != nil {
+		if errors.Is(err, clientdao.ErrClientNotFound) {
+			writeErrorJSON(w, http.StatusNotFound, err)
+			return
+		}
+
 		writeErrorJSON(w, http.StatusInternalServerError, err)
 		return
 	}
+	if !owns(user, client.UserID) {
+		writeErrorJSON(w, http.StatusForbidden, errForbidden)
+		return
+	}
+
+	key := imageKey(id, name)
+	if !hasImage(client.Images, key) {
+		writeErrorJSON(w, http.StatusNotFound, images.ErrImageNotFound)
+		return
+	}
+
+	// the entry goes first, an object left behind is only wasted space but
+	// an entry without its object is a broken image
+	err = s.clientDao.RemoveImage(ctx, id, key)
+	if err != nil {
+		if errors.Is(err, clientdao.ErrClientNotFound) || errors.Is(err, images.ErrImageNotFound) {
+			writeErrorJSON(w, http.StatusNotFound, err)
+			return
+		}
 
-	imageURL := fmt.Sprintf("servizio-be.appspot.com/%v", image.Key)
-	if err := s.clientDao.AppendImage(ctx, id, imageURL); err != nil {
 		writeErrorJSON(w, http.StatusInternalServerError, err)
 		return
 	}
+	s.deleteStoredImage(ctx, key)
 
-	writeJSON(w, http.StatusOK, "success")
+	writeJSON(w, http.StatusOK, "successful deletion")
 }
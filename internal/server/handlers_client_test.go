@@ -10,35 +10,14 @@ import (
 	"net/http/httptest"
 	"testing"
 
-	"cloud.google.com/go/firestore"
 	"github.com/devduck123/servizio-be/internal/clientdao"
-	"github.com/google/uuid"
 	"github.com/tj/assert"
 )
 
-func createTestClientDao(ctx context.Context, t *testing.T, keepCollection ...bool) *clientdao.Dao {
+func createTestClientDao(ctx context.Context, t *testing.T) clientdao.Store {
 	t.Helper()
 
-	client, err := firestore.NewClient(ctx, projectID)
-	assert.NoError(t, err)
-	clientCollection := fmt.Sprintf("client-%v", uuid.New())
-	dao := clientdao.NewDao(client, clientCollection)
-
-	cleanUp := true
-	for _, kc := range keepCollection {
-		if kc {
-			cleanUp = false
-			break
-		}
-	}
-
-	if cleanUp {
-		t.Cleanup(func() {
-			deleteCollection(ctx, t, client, clientCollection)
-		})
-	}
-
-	return dao
+	return clientdao.NewMemoryStore()
 }
 
 func TestCreateClient_Invalid(t *testing.T) {
@@ -148,9 +127,169 @@ func TestGetAllClients_Valid(t *testing.T) {
 	server.GetAllClients(w, r)
 	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
 
-	var response []clientdao.Client
+	var response struct {
+		Items []clientdao.Client `json:"items"`
+	}
 	err = json.NewDecoder(w.Result().Body).Decode(&response)
 	assert.NoError(t, err)
 
-	assert.Equal(t, 2, len(response))
+	assert.Equal(t, 2, len(response.Items))
+}
+
+func TestDeleteClient(t *testing.T) {
+	ctx := context.Background()
+	dao := createTestClientDao(ctx, t)
+	server := NewServer(nil, dao, nil, nil, nil)
+
+	client, err := dao.Create(ctx, clientdao.CreateInput{
+		FirstName: "foo",
+		LastName:  "bar",
+		UserID:    "client",
+	})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		user   string
+		status int
+	}{
+		{"not the owner", "stranger", http.StatusForbidden},
+		{"owner", "client", http.StatusOK},
+		{"not found", "client", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodDelete, "/clients/"+client.ID, nil)
+			r = r.WithContext(ContextWithUser(ctx, User{ID: tt.user}))
+
+			server.DeleteClient(w, r)
+			assert.Equal(t, tt.status, w.Result().StatusCode)
+		})
+	}
+}
+
+func TestCreateClient_AlreadyExists(t *testing.T) {
+	ctx := context.Background()
+	dao := createTestClientDao(ctx, t)
+	server := NewServer(nil, dao, nil, nil, nil)
+
+	for _, status := range []int{http.StatusOK, http.StatusConflict} {
+		w := httptest.NewRecorder()
+		body := bytes.NewReader([]byte(`{
+			"firstname":"foo",
+			"lastname":"bar"
+		}`))
+		r := httptest.NewRequest(http.MethodPost, "/", body)
+		r = r.WithContext(ContextWithUser(ctx, User{ID: "client"}))
+		server.CreateClient(w, r)
+		assert.Equal(t, status, w.Result().StatusCode)
+	}
+}
+
+func TestGetMyClient(t *testing.T) {
+	ctx := context.Background()
+	dao := createTestClientDao(ctx, t)
+	server := NewServer(nil, dao, nil, nil, nil)
+
+	client, err := dao.Create(ctx, clientdao.CreateInput{
+		FirstName: "foo",
+		LastName:  "bar",
+		UserID:    "client",
+	})
+	assert.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/clients/me", nil)
+	r = r.WithContext(ContextWithUser(ctx, User{ID: "client"}))
+	server.GetMyClient(w, r)
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+
+	var response clientdao.Client
+	err = json.NewDecoder(w.Result().Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, client.ID, response.ID)
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, "/clients/me", nil)
+	r = r.WithContext(ContextWithUser(ctx, User{ID: "stranger"}))
+	server.GetMyClient(w, r)
+	assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+}
+
+func TestUpdateClient(t *testing.T) {
+	ctx := context.Background()
+	dao := createTestClientDao(ctx, t)
+	server := NewServer(nil, dao, nil, nil, nil)
+
+	client, err := dao.Create(ctx, clientdao.CreateInput{
+		FirstName: "foo",
+		LastName:  "bar",
+		UserID:    "client",
+	})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		user   string
+		body   string
+		status int
+	}{
+		{"not the owner", "stranger", `{"firstname":"baz"}`, http.StatusForbidden},
+		{"empty name", "client", `{"firstname":""}`, http.StatusBadRequest},
+		{"first name only", "client", `{"firstname":"baz"}`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPatch, "/clients/"+client.ID, bytes.NewReader([]byte(tt.body)))
+			r = r.WithContext(ContextWithUser(ctx, User{ID: tt.user}))
+
+			server.UpdateClient(w, r)
+			assert.Equal(t, tt.status, w.Result().StatusCode)
+		})
+	}
+
+	updated, err := dao.GetClient(ctx, client.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "baz", updated.FirstName)
+	assert.Equal(t, "bar", updated.LastName)
+}
+
+func TestGetAllClients_Pages(t *testing.T) {
+	ctx := context.Background()
+	dao := createTestClientDao(ctx, t)
+	server := NewServer(nil, dao, nil, nil, nil)
+
+	for _, name := range []string{"foo", "bar", "baz"} {
+		_, err := dao.Create(ctx, clientdao.CreateInput{
+			FirstName: name,
+			LastName:  name,
+		})
+		assert.NoError(t, err)
+	}
+
+	var pages []int
+	url := "/clients?limit=2"
+	for url != "" {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, url, nil)
+		server.GetAllClients(w, r)
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+
+		var response struct {
+			Items      []clientdao.Client `json:"items"`
+			NextCursor string             `json:"nextCursor"`
+		}
+		err := json.NewDecoder(w.Result().Body).Decode(&response)
+		assert.NoError(t, err)
+		pages = append(pages, len(response.Items))
+
+		url = ""
+		if response.NextCursor != "" {
+			url = "/clients?limit=2&cursor=" + response.NextCursor
+		}
+	}
+
+	assert.Equal(t, []int{2, 1}, pages)
 }
@@ -0,0 +1,145 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/devduck123/servizio-be/internal/images"
+	"github.com/devduck123/servizio-be/internal/logging"
+)
+
+// imageCacheControl lets the browser of the user keep an image for a day,
+// but not shared caches, as images are only sent to signed in users.
+const imageCacheControl = "private, max-age=86400"
+
+// imageContentTypes are the types images are sent as. Anything else is sent
+// as a download, so that it is never rendered as a page of the API.
+var imageContentTypes = map[string]bool{
+	images.ContentTypeJPEG: true,
+	images.ContentTypePNG:  true,
+	images.ContentTypeWebP: true,
+}
+
+func (s *Server) ImageRouter(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		s.Authenticate(s.GetImage)(w, r)
+		return
+	case http.MethodOptions:
+		return
+	default:
+		writeErrorJSON(w, http.StatusNotImplemented, fmt.Errorf("%v not implemented yet", r.Method))
+	}
+}
+
+// GetImage streams the image stored at the key following /images/, or
+// its variant of the size given by the size parameter. Only images of a
+// business or client are served, not ones removed from them.
+func (s *Server) GetImage(w http.ResponseWriter, r *http.Request) {
+	logging.FromContext(r.Context()).Debug("GetImage called", "path", r.URL.Path)
+
+	ctx := r.Context()
+
+	key := strings.TrimPrefix(r.URL.Path, images.URLPath)
+	if !validImageKey(key) {
+		writeErrorJSON(w, http.StatusNotFound, images.ErrImageNotFound)
+		return
+	}
+	referenced, err := s.imageReferenced(ctx, key)
+	if err != nil {
+		writeErrorJSON(w, http.StatusInternalServerError, err)
+		return
+	}
+	if !referenced {
+		writeErrorJSON(w, http.StatusNotFound, images.ErrImageNotFound)
+		return
+	}
+	if sizeParam := r.URL.Query().Get("size"); sizeParam != "" {
+		size, err := strconv.Atoi(sizeParam)
+		if err != nil || !isVariantSize(size) {
+			writeErrorJSON(w, http.StatusBadRequest, fmt.Errorf("size must be one of %v", images.VariantSizes))
+			return
+		}
+		key = images.VariantKey(key, size)
+	}
+
+	object, err := s.imageManager.OpenImage(ctx, key)
+	if err != nil {
+		if errors.Is(err, images.ErrImageNotFound) {
+			writeErrorJSON(w, http.StatusNotFound, err)
+			return
+		}
+
+		writeErrorJSON(w, http.StatusInternalServerError, err)
+		return
+	}
+	defer object.Close()
+
+	etag := strconv.Quote(object.ETag)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", imageCacheControl)
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	if imageContentTypes[object.ContentType] {
+		w.Header().Set("Content-Type", object.ContentType)
+	} else {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", "attachment")
+	}
+	w.Header().Set("Content-Length", strconv.FormatInt(object.Size, 10))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	if _, err := io.Copy(w, object); err != nil {
+		// the status is sent already, all that is left is to log it
+		logging.FromContext(ctx).Warn("error streaming image", "key", key, "error", err)
+	}
+}
+
+// validImageKey rejects keys that cannot have been given by UploadImage,
+// such as ones climbing out with "..".
+func validImageKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+func isVariantSize(size int) bool {
+	for _, variantSize := range images.VariantSizes {
+		if size == variantSize {
+			return true
+		}
+	}
+	return false
+}
+
+// etagMatches reports whether an If-None-Match header lists etag, using
+// the weak comparison the header calls for.
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,152 @@
+package server
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/devduck123/servizio-be/internal/authtest"
+	"github.com/devduck123/servizio-be/internal/authtoken"
+	"github.com/devduck123/servizio-be/internal/businessdao"
+	"github.com/devduck123/servizio-be/internal/clientdao"
+	"github.com/devduck123/servizio-be/internal/images"
+	"github.com/tj/assert"
+)
+
+func TestGetImage(t *testing.T) {
+	ctx := context.Background()
+	businessDao := businessdao.NewMemoryStore()
+	clientDao := clientdao.NewMemoryStore()
+	im := images.NewMemoryStore()
+	server := NewServer(businessDao, clientDao, nil, im, nil, WithTokenVerifier(authtest.Verifier(projectID)))
+
+	business, err := businessDao.Create(ctx, businessdao.CreateInput{Name: "foo", UserID: "owner"})
+	assert.NoError(t, err)
+	upload, err := images.Prepare(readSampleImage(t, "sample.png"))
+	assert.NoError(t, err)
+	image, err := im.UploadImage(ctx, business.ID, upload)
+	assert.NoError(t, err)
+	assert.NoError(t, businessDao.AppendImage(ctx, business.ID, image.Ref()))
+
+	token := mintToken(t, authtoken.MintInput{UID: "someone", SignInProvider: "phone"})
+	get := func(method, target, ifNoneMatch string) *http.Response {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(method, target, nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		if ifNoneMatch != "" {
+			r.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		server.ImageRouter(w, r)
+		return w.Result()
+	}
+
+	resp := get(http.MethodGet, images.URL(image.Key), "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, images.ContentTypePNG, resp.Header.Get("Content-Type"))
+	assert.Equal(t, imageCacheControl, resp.Header.Get("Cache-Control"))
+	body, err := ioutil.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, upload.Raw, body)
+	etag := resp.Header.Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	for _, ifNoneMatch := range []string{etag, "W/" + etag, `"other", ` + etag, "*"} {
+		resp := get(http.MethodGet, images.URL(image.Key), ifNoneMatch)
+		assert.Equal(t, http.StatusNotModified, resp.StatusCode, ifNoneMatch)
+		assert.Equal(t, etag, resp.Header.Get("ETag"))
+		body, err := ioutil.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		assert.Empty(t, body)
+	}
+	resp = get(http.MethodGet, images.URL(image.Key), `"other"`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = get(http.MethodGet, images.URL(image.Key)+"?size=128", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, images.VariantContentType, resp.Header.Get("Content-Type"))
+	assert.NotEqual(t, etag, resp.Header.Get("ETag"))
+	body, err = ioutil.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, upload.Variants[0].Raw, body)
+
+	resp = get(http.MethodHead, images.URL(image.Key), "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err = ioutil.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.Empty(t, body)
+
+	// images of clients are served too
+	client, err := clientDao.Create(ctx, clientdao.CreateInput{FirstName: "foo", UserID: "owner"})
+	assert.NoError(t, err)
+	clientImage, err := im.UploadImage(ctx, client.ID, upload)
+	assert.NoError(t, err)
+	assert.NoError(t, clientDao.AppendImage(ctx, client.ID, clientImage.Ref()))
+	resp = get(http.MethodGet, images.URL(clientImage.Key), "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// stored images that are not of a business or client
+	unreferenced, err := im.UploadImage(ctx, business.ID, upload)
+	assert.NoError(t, err)
+	removed, err := im.UploadImage(ctx, client.ID, upload)
+	assert.NoError(t, err)
+	assert.NoError(t, clientDao.AppendImage(ctx, client.ID, removed.Ref()))
+	assert.NoError(t, clientDao.RemoveImage(ctx, client.ID, removed.Key))
+	orphan, err := im.UploadImage(ctx, "notexists", upload)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		target string
+		status int
+	}{
+		{images.URL(image.Key) + "?size=100", http.StatusBadRequest},
+		{images.URL(image.Key) + "?size=large", http.StatusBadRequest},
+		{images.URL(business.ID + "/notexists"), http.StatusNotFound},
+		{images.URL(unreferenced.Key), http.StatusNotFound},
+		{images.URL(unreferenced.Key) + "?size=128", http.StatusNotFound},
+		{images.URL(removed.Key), http.StatusNotFound},
+		{images.URL(orphan.Key), http.StatusNotFound},
+		{images.URL("foo//" + image.Key), http.StatusNotFound},
+		{images.URL("foo/../" + image.Key), http.StatusNotFound},
+		{images.URLPath, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		resp := get(http.MethodGet, tt.target, "")
+		assert.Equal(t, tt.status, resp.StatusCode, tt.target)
+	}
+
+	w := httptest.NewRecorder()
+	server.ImageRouter(w, httptest.NewRequest(http.MethodGet, images.URL(image.Key), nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Result().StatusCode)
+}
+
+func TestGetImage_NotAnImage(t *testing.T) {
+	ctx := context.Background()
+	businessDao := businessdao.NewMemoryStore()
+	im := images.NewMemoryStore()
+	server := NewServer(businessDao, clientdao.NewMemoryStore(), nil, im, nil, WithTokenVerifier(authtest.Verifier(projectID)))
+
+	business, err := businessDao.Create(ctx, businessdao.CreateInput{Name: "foo", UserID: "owner"})
+	assert.NoError(t, err)
+	page := []byte("<script>alert(1)</script>")
+	for _, contentType := range []string{"text/html", ""} {
+		image, err := im.UploadImage(ctx, business.ID, images.Upload{ContentType: contentType, Raw: page})
+		assert.NoError(t, err)
+		assert.NoError(t, businessDao.AppendImage(ctx, business.ID, image.Ref()))
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, images.URL(image.Key), nil)
+		r.Header.Set("Authorization", "Bearer "+mintToken(t, authtoken.MintInput{UID: "someone", SignInProvider: "phone"}))
+		server.ImageRouter(w, r)
+
+		resp := w.Result()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "application/octet-stream", resp.Header.Get("Content-Type"))
+		assert.Equal(t, "attachment", resp.Header.Get("Content-Disposition"))
+		assert.Equal(t, "nosniff", resp.Header.Get("X-Content-Type-Options"))
+		body, err := ioutil.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, page, body)
+	}
+}
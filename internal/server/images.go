@@ -0,0 +1,124 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/devduck123/servizio-be/internal/businessdao"
+	"github.com/devduck123/servizio-be/internal/clientdao"
+	"github.com/devduck123/servizio-be/internal/images"
+	"github.com/devduck123/servizio-be/internal/logging"
+)
+
+// defaultMaxImageBytes is the largest image accepted without WithMaxImageBytes.
+const defaultMaxImageBytes = 10 << 20
+
+var (
+	errNoImage       = errors.New("no image provided")
+	errImageTooLarge = errors.New("image is too large")
+)
+
+// WithMaxImageBytes sets the largest image that can be uploaded.
+func WithMaxImageBytes(n int64) Option {
+	return func(s *Server) {
+		s.maxImageBytes = n
+	}
+}
+
+// readImage reads the image in the body of r, rejecting bodies larger than
+// the limit before reading them whole, validates it and creates its variants.
+func (s *Server) readImage(w http.ResponseWriter, r *http.Request) (images.Upload, error) {
+	if r.ContentLength > s.maxImageBytes {
+		return images.Upload{}, errImageTooLarge
+	}
+
+	raw, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, s.maxImageBytes))
+	if err != nil {
+		// MaxBytesReader stops at the limit, http.MaxBytesError is not
+		// available before go 1.19
+		if int64(len(raw)) >= s.maxImageBytes {
+			return images.Upload{}, errImageTooLarge
+		}
+		return images.Upload{}, err
+	}
+	if len(raw) == 0 {
+		return images.Upload{}, errNoImage
+	}
+
+	return images.Prepare(raw)
+}
+
+// imagePath splits the path of an image of a business or client,
+// <prefix>/<id>/images/<name>, returning false if it is not one.
+func imagePath(urlPath, prefix string) (id, name string, ok bool) {
+	trimmed := strings.TrimSuffix(strings.TrimPrefix(urlPath, prefix), "/")
+	parts := strings.Split(trimmed, "/")
+	if len(parts) != 3 || parts[0] == "" || parts[1] != "images" || parts[2] == "" {
+		return "", "", false
+	}
+	return parts[0], parts[2], true
+}
+
+// imageKey returns the key of an image of the business or client with the
+// given ID, images are stored under the ID of their owner.
+func imageKey(id, name string) string {
+	return fmt.Sprintf("%s/%s", id, name)
+}
+
+// hasImage reports whether refs holds the image with the given key.
+func hasImage(refs []images.Ref, key string) bool {
+	for _, ref := range refs {
+		if ref.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// imageReferenced reports whether the image with the given key is one of
+// the images of the business or client it is stored under.
+func (s *Server) imageReferenced(ctx context.Context, key string) (bool, error) {
+	ownerID := strings.SplitN(key, "/", 2)[0]
+
+	business, err := s.businessDao.GetBusiness(ctx, ownerID)
+	if err != nil && !errors.Is(err, businessdao.ErrBusinessNotFound) {
+		return false, err
+	}
+	if business != nil && hasImage(business.Images, key) {
+		return true, nil
+	}
+
+	client, err := s.clientDao.GetClient(ctx, ownerID)
+	if err != nil && !errors.Is(err, clientdao.ErrClientNotFound) {
+		return false, err
+	}
+	return client != nil && hasImage(client.Images, key), nil
+}
+
+// deleteStoredImage removes an image and its variants from storage once
+// its entry is gone. An image that cannot be removed is only logged, it is
+// no longer served and can be cleaned up later.
+func (s *Server) deleteStoredImage(ctx context.Context, key string) {
+	err := s.imageManager.DeleteImage(ctx, key)
+	if err != nil && !errors.Is(err, images.ErrImageNotFound) {
+		logging.FromContext(ctx).Warn("error deleting image, leaving it orphaned", "key", key, "error", err)
+	}
+}
+
+// writeImageError answers with the status matching an error of readImage.
+func (s *Server) writeImageError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errImageTooLarge):
+		writeErrorJSON(w, http.StatusRequestEntityTooLarge, fmt.Errorf("%w, the limit is %v bytes", err, s.maxImageBytes))
+	case errors.Is(err, images.ErrUnsupportedType):
+		writeErrorJSON(w, http.StatusUnsupportedMediaType, err)
+	case errors.Is(err, images.ErrInvalidImage):
+		writeErrorJSON(w, http.StatusBadRequest, images.ErrInvalidImage)
+	default:
+		writeErrorJSON(w, http.StatusBadRequest, err)
+	}
+}
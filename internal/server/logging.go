@@ -0,0 +1,112 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+	"unicode"
+
+	"github.com/devduck123/servizio-be/internal/logging"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	requestIDHeader = "X-Request-ID"
+	// maxRequestIDLength bounds the request IDs accepted from callers
+	maxRequestIDLength = 128
+)
+
+// requestInfo is filled in while the request goes through the middleware,
+// the access log reads it once the handler returns.
+type requestInfo struct {
+	userID string
+}
+
+type requestInfoKey struct{}
+
+// setRequestUser records the authenticated user for the access log and
+// returns a context whose logger includes the user ID.
+func setRequestUser(ctx context.Context, userID string) context.Context {
+	if info, ok := ctx.Value(requestInfoKey{}).(*requestInfo); ok {
+		info.userID = userID
+	}
+	return logging.NewContext(ctx, logging.FromContext(ctx).With("userId", userID))
+}
+
+// httpRequest is the structured HTTP request of a Cloud Logging entry.
+type httpRequest struct {
+	RequestMethod string `json:"requestMethod"`
+	RequestURL    string `json:"requestUrl"`
+	Status        int    `json:"status"`
+	ResponseSize  string `json:"responseSize"`
+	Latency       string `json:"latency"`
+	UserAgent     string `json:"userAgent,omitempty"`
+	RemoteIP      string `json:"remoteIp,omitempty"`
+}
+
+// Logger gives every request an ID, taken from the X-Request-ID header when
+// the caller sent a usable one, and writes an access log entry once the
+// handler returns. Handlers log through logging.FromContext to have the
+// request ID, and the user ID once authenticated, on their entries.
+func (s *Server) Logger(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		requestID := r.Header.Get(requestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = uuid.New().String()
+		}
+		w.Header().Set(requestIDHeader, requestID)
+
+		logger := logging.FromContext(r.Context()).With("requestId", requestID)
+		if span := trace.SpanContextFromContext(r.Context()); span.IsValid() {
+			logger = logger.With("traceId", span.TraceID().String(), "spanId", span.SpanID().String())
+		}
+		info := &requestInfo{}
+		ctx := context.WithValue(r.Context(), requestInfoKey{}, info)
+		ctx = logging.NewContext(ctx, logger)
+
+		rw := NewResponseWriter(w)
+		next(rw, r.WithContext(ctx))
+
+		level := logging.LevelInfo
+		if rw.Status() >= http.StatusInternalServerError {
+			level = logging.LevelError
+		}
+		keyvals := []interface{}{
+			"httpRequest", httpRequest{
+				RequestMethod: r.Method,
+				RequestURL:    r.URL.RequestURI(),
+				Status:        rw.Status(),
+				ResponseSize:  strconv.Itoa(rw.Size()),
+				Latency:       fmt.Sprintf("%.6fs", time.Since(start).Seconds()),
+				UserAgent:     r.UserAgent(),
+				RemoteIP:      r.RemoteAddr,
+			},
+		}
+		if info.userID != "" {
+			keyvals = append(keyvals, "userId", info.userID)
+		}
+		if rw.Err() != nil {
+			// for server errors this is the only place the cause shows up
+			keyvals = append(keyvals, "error", rw.Err())
+		}
+		logger.Log(level, fmt.Sprintf("%v %v %d", r.Method, r.URL.Path, rw.Status()), keyvals...)
+	}
+}
+
+// validRequestID keeps callers from putting arbitrary text in our logs.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		if c > unicode.MaxASCII || !unicode.IsPrint(c) || c == ' ' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,159 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/devduck123/servizio-be/internal/appointmentdao"
+	"github.com/devduck123/servizio-be/internal/authtest"
+	"github.com/devduck123/servizio-be/internal/authtoken"
+	"github.com/devduck123/servizio-be/internal/logging"
+	"github.com/tj/assert"
+)
+
+// logEntries decodes the JSON lines written to buf.
+func logEntries(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+
+	var entries []map[string]interface{}
+	scanner := bufio.NewScanner(buf)
+	for scanner.Scan() {
+		var entry map[string]interface{}
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestLogger_AccessLog(t *testing.T) {
+	server := NewServer(nil, nil, nil, nil, nil, WithTokenVerifier(authtest.Verifier(projectID)))
+	handler := server.Logger(server.Authenticate(func(w http.ResponseWriter, r *http.Request) {
+		logging.FromContext(r.Context()).Info("booking")
+		writeErrorJSON(w, http.StatusConflict, appointmentdao.ErrAppointmentConflict)
+	}))
+
+	var buf bytes.Buffer
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/appointments/?x=1", nil)
+	r = r.WithContext(logging.NewContext(r.Context(), logging.New(&buf, logging.LevelDebug)))
+	r.Header.Set("Authorization", "Bearer "+mintToken(t, authtoken.MintInput{UID: "foo", SignInProvider: "phone"}))
+	r.Header.Set(requestIDHeader, "abc-123")
+
+	handler(w, r)
+	assert.Equal(t, http.StatusConflict, w.Result().StatusCode)
+	assert.Equal(t, "abc-123", w.Result().Header.Get(requestIDHeader))
+
+	entries := logEntries(t, &buf)
+	assert.Len(t, entries, 2)
+
+	// the handler log carries the request and user IDs
+	assert.Equal(t, "booking", entries[0]["message"])
+	assert.Equal(t, "INFO", entries[0]["severity"])
+	assert.Equal(t, "abc-123", entries[0]["requestId"])
+	assert.Equal(t, "foo", entries[0]["userId"])
+
+	access := entries[1]
+	assert.Equal(t, "INFO", access["severity"])
+	assert.Equal(t, "abc-123", access["requestId"])
+	assert.Equal(t, "foo", access["userId"])
+	httpRequest := access["httpRequest"].(map[string]interface{})
+	assert.Equal(t, "POST", httpRequest["requestMethod"])
+	assert.Equal(t, "/appointments/?x=1", httpRequest["requestUrl"])
+	assert.Equal(t, float64(http.StatusConflict), httpRequest["status"])
+	assert.Regexp(t, `^\d+\.\d{6}s$`, httpRequest["latency"])
+}
+
+func TestLogger_RequestID(t *testing.T) {
+	server := NewServer(nil, nil, nil, nil, nil)
+	handler := server.Logger(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, "ok")
+	})
+
+	tests := map[string]struct {
+		header string
+		kept   bool
+	}{
+		"missing":      {header: "", kept: false},
+		"valid":        {header: "0f8fad5b-d9cb-469f-a165-70867728950e", kept: true},
+		"with newline": {header: "abc\ninjected", kept: false},
+		"with space":   {header: "abc def", kept: false},
+		"too long":     {header: string(bytes.Repeat([]byte("a"), maxRequestIDLength+1)), kept: false},
+		"maximum long": {header: string(bytes.Repeat([]byte("a"), maxRequestIDLength)), kept: true},
+		"non ascii":    {header: "réservation", kept: false},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/businesses/", nil)
+			r = r.WithContext(logging.NewContext(r.Context(), logging.New(&buf, logging.LevelInfo)))
+			if tt.header != "" {
+				r.Header.Set(requestIDHeader, tt.header)
+			}
+
+			handler(w, r)
+			requestID := w.Result().Header.Get(requestIDHeader)
+			assert.NotEmpty(t, requestID)
+			if tt.kept {
+				assert.Equal(t, tt.header, requestID)
+			} else {
+				assert.NotEqual(t, tt.header, requestID)
+			}
+
+			entries := logEntries(t, &buf)
+			assert.Len(t, entries, 1)
+			assert.Equal(t, requestID, entries[0]["requestId"])
+			assert.Nil(t, entries[0]["userId"])
+		})
+	}
+}
+
+func TestLogger_ServerErrorsAreErrors(t *testing.T) {
+	server := NewServer(nil, nil, nil, nil, nil)
+	handler := server.Logger(func(w http.ResponseWriter, r *http.Request) {
+		writeErrorJSON(w, http.StatusInternalServerError, errors.New("firestore unavailable"))
+	})
+
+	var buf bytes.Buffer
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/businesses/", nil)
+	r = r.WithContext(logging.NewContext(r.Context(), logging.New(&buf, logging.LevelInfo)))
+	handler(w, r)
+
+	// the client does not learn about the cause
+	assert.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
+	assert.Equal(t, `{"error":"Internal Server Error"}`, w.Body.String())
+
+	entries := logEntries(t, &buf)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "ERROR", entries[0]["severity"])
+	assert.Equal(t, "firestore unavailable", entries[0]["error"])
+	httpRequest := entries[0]["httpRequest"].(map[string]interface{})
+	assert.Equal(t, float64(http.StatusInternalServerError), httpRequest["status"])
+	assert.Equal(t, strconv.Itoa(w.Body.Len()), httpRequest["responseSize"])
+}
+
+func TestLogger_ClientErrorsKeepTheirMessage(t *testing.T) {
+	server := NewServer(nil, nil, nil, nil, nil)
+	handler := server.Logger(func(w http.ResponseWriter, r *http.Request) {
+		writeErrorJSON(w, http.StatusConflict, appointmentdao.ErrAppointmentConflict)
+	})
+
+	var buf bytes.Buffer
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/appointments/", nil)
+	r = r.WithContext(logging.NewContext(r.Context(), logging.New(&buf, logging.LevelInfo)))
+	handler(w, r)
+
+	assert.Contains(t, w.Body.String(), appointmentdao.ErrAppointmentConflict.Error())
+	entries := logEntries(t, &buf)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "INFO", entries[0]["severity"])
+	assert.Equal(t, appointmentdao.ErrAppointmentConflict.Error(), entries[0]["error"])
+}
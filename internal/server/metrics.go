@@ -0,0 +1,29 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/devduck123/servizio-be/internal/metrics"
+)
+
+// WithMetrics records the requests seen by the Metrics middleware on m.
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(s *Server) {
+		s.metrics = m
+	}
+}
+
+// Metrics counts the requests to route by status code, times them and
+// tracks how many are in flight. Without WithMetrics it returns next as is.
+func (s *Server) Metrics(route string, next http.HandlerFunc) http.HandlerFunc {
+	if s.metrics == nil {
+		return next
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		rw := NewResponseWriter(w)
+		done := s.metrics.TrackRequest(route, r.Method)
+		next(rw, r)
+		done(rw.Status())
+	}
+}
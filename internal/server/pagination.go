@@ -0,0 +1,54 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/devduck123/servizio-be/internal/pagination"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// listResponse is the envelope of every list endpoint. NextCursor is
+// passed back as the cursor query parameter to get the following page,
+// it is left out on the last page.
+type listResponse struct {
+	Items      interface{} `json:"items"`
+	NextCursor string      `json:"nextCursor,omitempty"`
+}
+
+// pageFromRequest reads the limit and cursor query parameters.
+func pageFromRequest(r *http.Request) (pagination.Input, error) {
+	page := pagination.Input{
+		Limit: defaultPageLimit,
+	}
+
+	if rawLimit := r.URL.Query().Get("limit"); rawLimit != "" {
+		limit, err := strconv.Atoi(rawLimit)
+		if err != nil || limit < 1 || limit > maxPageLimit {
+			return pagination.Input{}, fmt.Errorf("limit must be a number between 1 and %d", maxPageLimit)
+		}
+		page.Limit = limit
+	}
+
+	// the cursor is checked by the store, which knows how the list is
+	// ordered
+	page.StartAfter = r.URL.Query().Get("cursor")
+
+	return page, nil
+}
+
+// writeListError writes the response for an error returned by a paged DAO call.
+func writeListError(w http.ResponseWriter, err error) {
+	if errors.Is(err, pagination.ErrInvalidCursor) {
+		writeErrorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+
+	writeErrorJSON(w, http.StatusInternalServerError, err)
+}
@@ -0,0 +1,38 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/devduck123/servizio-be/internal/pagination"
+	"github.com/tj/assert"
+)
+
+func TestPageFromRequest(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/businesses", nil)
+	page, err := pageFromRequest(r)
+	assert.NoError(t, err)
+	assert.Equal(t, pagination.Input{Limit: defaultPageLimit}, page)
+
+	cursor := pagination.Cursor{ID: "foo"}.Encode()
+	r = httptest.NewRequest(http.MethodGet, "/businesses?limit=5&cursor="+cursor, nil)
+	page, err = pageFromRequest(r)
+	assert.NoError(t, err)
+	assert.Equal(t, pagination.Input{Limit: 5, StartAfter: cursor}, page)
+}
+
+func TestPageFromRequest_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"limit not a number": "limit=ten",
+		"limit too small":    "limit=0",
+		"limit too big":      "limit=1000",
+	}
+	for name, query := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/businesses?"+query, nil)
+			_, err := pageFromRequest(r)
+			assert.Error(t, err)
+		})
+	}
+}
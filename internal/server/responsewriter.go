@@ -0,0 +1,73 @@
+package server
+
+import (
+	"net/http"
+)
+
+// ResponseWriter records what the handler wrote, the status code, the
+// number of body bytes and the error passed to writeErrorJSON, so that
+// middleware running after the handler, like Logger, can report on it.
+type ResponseWriter struct {
+	http.ResponseWriter
+	status      int
+	size        int
+	wroteHeader bool
+	err         error
+}
+
+// NewResponseWriter wraps w, or returns it as is if it already is a
+// *ResponseWriter so that stacked middleware share one recording.
+func NewResponseWriter(w http.ResponseWriter) *ResponseWriter {
+	if rw, ok := w.(*ResponseWriter); ok {
+		return rw
+	}
+	return &ResponseWriter{ResponseWriter: w}
+}
+
+func (rw *ResponseWriter) WriteHeader(status int) {
+	if rw.wroteHeader {
+		return
+	}
+	rw.status = status
+	rw.wroteHeader = true
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+func (rw *ResponseWriter) Write(b []byte) (int, error) {
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+	n, err := rw.ResponseWriter.Write(b)
+	rw.size += n
+	return n, err
+}
+
+// Status returns the status code sent, 200 if the handler wrote nothing
+// as net/http does.
+func (rw *ResponseWriter) Status() int {
+	if !rw.wroteHeader {
+		return http.StatusOK
+	}
+	return rw.status
+}
+
+// Size returns the number of body bytes written.
+func (rw *ResponseWriter) Size() int {
+	return rw.size
+}
+
+// Err returns the error the handler answered with, nil if none.
+func (rw *ResponseWriter) Err() error {
+	return rw.err
+}
+
+func (rw *ResponseWriter) Flush() {
+	if flusher, ok := rw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the wrapped writer, used by http.ResponseController.
+func (rw *ResponseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
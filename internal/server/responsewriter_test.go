@@ -0,0 +1,50 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tj/assert"
+)
+
+func TestResponseWriter(t *testing.T) {
+	rec := httptest.NewRecorder()
+	rw := NewResponseWriter(rec)
+
+	// nothing written yet, net/http would send 200
+	assert.Equal(t, http.StatusOK, rw.Status())
+	assert.Equal(t, 0, rw.Size())
+
+	rw.WriteHeader(http.StatusCreated)
+	// a second call is ignored like net/http does
+	rw.WriteHeader(http.StatusInternalServerError)
+	n, err := rw.Write([]byte("hello"))
+	assert.NoError(t, err)
+	assert.Equal(t, 5, n)
+	rw.Write([]byte(" world"))
+
+	assert.Equal(t, http.StatusCreated, rw.Status())
+	assert.Equal(t, 11, rw.Size())
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "hello world", rec.Body.String())
+
+	// stacked middleware share the recording
+	assert.Equal(t, rw, NewResponseWriter(rw))
+}
+
+func TestResponseWriter_ImplicitStatus(t *testing.T) {
+	rw := NewResponseWriter(httptest.NewRecorder())
+	rw.Write([]byte("{}"))
+	assert.Equal(t, http.StatusOK, rw.Status())
+	assert.Equal(t, 2, rw.Size())
+}
+
+func TestResponseWriter_Err(t *testing.T) {
+	rw := NewResponseWriter(httptest.NewRecorder())
+	assert.NoError(t, rw.Err())
+
+	writeErrorJSON(rw, http.StatusBadRequest, errForbidden)
+	assert.Equal(t, errForbidden, rw.Err())
+	assert.Equal(t, http.StatusBadRequest, rw.Status())
+}
@@ -0,0 +1,171 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	firebase "firebase.google.com/go/v4"
+	"firebase.google.com/go/v4/auth"
+	"github.com/devduck123/servizio-be/internal/logging"
+)
+
+type Role string
+
+var (
+	RoleAdmin         Role = "admin"
+	RoleBusinessOwner Role = "business_owner"
+	RoleStaff         Role = "staff"
+	RoleClient        Role = "client"
+)
+
+func (r Role) IsValid() bool {
+	allRoles := []Role{RoleAdmin, RoleBusinessOwner, RoleStaff, RoleClient}
+	for _, role := range allRoles {
+		if r == role {
+			return true
+		}
+	}
+
+	return false
+}
+
+// rolesClaim is the custom claim holding the roles of a user.
+const rolesClaim = "roles"
+
+var ErrUserNotFound = errors.New("user not found")
+
+// HasRole reports whether the user has any of the given roles.
+func (u User) HasRole(roles ...Role) bool {
+	for _, have := range u.Roles {
+		for _, want := range roles {
+			if have == want {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// rolesFromClaims reads the roles custom claim, skipping unknown roles.
+func rolesFromClaims(claims map[string]interface{}) []Role {
+	rawRoles, _ := claims[rolesClaim].([]interface{})
+
+	var roles []Role
+	for _, rawRole := range rawRoles {
+		role, _ := rawRole.(string)
+		if Role(role).IsValid() {
+			roles = append(roles, Role(role))
+		}
+	}
+
+	return roles
+}
+
+// RoleManager stores the roles of a user.
+type RoleManager interface {
+	SetRoles(ctx context.Context, uid string, roles []Role) error
+}
+
+// WithRoleManager replaces Firebase Auth custom claims as where roles are set.
+func WithRoleManager(manager RoleManager) Option {
+	return func(s *Server) {
+		s.roleManager = manager
+	}
+}
+
+// firebaseRoleManager sets roles as custom claims through Firebase Auth.
+type firebaseRoleManager struct {
+	app *firebase.App
+}
+
+func (m firebaseRoleManager) SetRoles(ctx context.Context, uid string, roles []Role) error {
+	client, err := m.app.Auth(ctx)
+	if err != nil {
+		return err
+	}
+
+	userRecord, err := client.GetUser(ctx, uid)
+	if err != nil {
+		if auth.IsUserNotFound(err) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+
+	// setting custom claims replaces all of them, keep the other ones
+	claims := make(map[string]interface{}, len(userRecord.CustomClaims)+1)
+	for name, value := range userRecord.CustomClaims {
+		claims[name] = value
+	}
+	claims[rolesClaim] = roles
+
+	return client.SetCustomUserClaims(ctx, uid, claims)
+}
+
+// RequireRole lets through users with any of the given roles. It must run
+// after Authenticate.
+func (s *Server) RequireRole(next http.HandlerFunc, roles ...Role) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, err := UserFromContext(r.Context())
+		if err != nil {
+			writeErrorJSON(w, http.StatusUnauthorized, err)
+			return
+		}
+		if !user.HasRole(roles...) {
+			writeErrorJSON(w, http.StatusForbidden, errors.New("you do not have the role required"))
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+type SetUserRolesInput struct {
+	Roles []Role `json:"roles"`
+}
+
+// SetUserRoles replaces the roles of a user. They show up in the user's
+// tokens once the app refreshes them.
+func (s *Server) SetUserRoles(w http.ResponseWriter, r *http.Request) {
+	logging.FromContext(r.Context()).Debug("SetUserRoles called", "path", r.URL.Path)
+
+	trimmedURL := strings.TrimSuffix(r.URL.Path, "/")
+	uid := strings.TrimSuffix(strings.TrimPrefix(trimmedURL, "/admin/users/"), "/roles")
+	if uid == "" || strings.Contains(uid, "/") {
+		writeErrorJSON(w, http.StatusNotFound, ErrUserNotFound)
+		return
+	}
+
+	var setUserRolesInput SetUserRolesInput
+	err := json.NewDecoder(r.Body).Decode(&setUserRolesInput)
+	if err != nil {
+		writeErrorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	roles := make([]Role, 0, len(setUserRolesInput.Roles))
+	for _, role := range setUserRolesInput.Roles {
+		if !role.IsValid() {
+			writeErrorJSON(w, http.StatusBadRequest, fmt.Errorf("invalid role %q", role))
+			return
+		}
+		roles = append(roles, role)
+	}
+
+	err = s.roleManager.SetRoles(r.Context(), uid, roles)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			writeErrorJSON(w, http.StatusNotFound, err)
+			return
+		}
+
+		writeErrorJSON(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, setUserRolesInput)
+}
@@ -0,0 +1,114 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/devduck123/servizio-be/internal/authtest"
+	"github.com/devduck123/servizio-be/internal/authtoken"
+	"github.com/tj/assert"
+)
+
+type fakeRoleManager struct {
+	roles map[string][]Role
+}
+
+func (f *fakeRoleManager) SetRoles(ctx context.Context, uid string, roles []Role) error {
+	if _, ok := f.roles[uid]; !ok {
+		return ErrUserNotFound
+	}
+	f.roles[uid] = roles
+	return nil
+}
+
+func TestAuthenticate_Roles(t *testing.T) {
+	server := NewServer(nil, nil, nil, nil, nil, WithTokenVerifier(authtest.Verifier(projectID)))
+
+	var gotUser User
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Authorization", "Bearer "+mintToken(t, authtoken.MintInput{
+		UID:            "foo",
+		SignInProvider: "phone",
+		Claims: map[string]interface{}{
+			"roles": []string{"staff", "superuser"},
+		},
+	}))
+	server.Authenticate(func(w http.ResponseWriter, r *http.Request) {
+		gotUser, _ = UserFromContext(r.Context())
+	})(w, r)
+
+	assert.Equal(t, []Role{RoleStaff}, gotUser.Roles)
+}
+
+func TestRequireRole(t *testing.T) {
+	server := NewServer(nil, nil, nil, nil, nil)
+
+	tests := []struct {
+		name   string
+		roles  []Role
+		status int
+	}{
+		{"no roles", nil, http.StatusForbidden},
+		{"other role", []Role{RoleClient}, http.StatusForbidden},
+		{"one of the roles", []Role{RoleClient, RoleStaff}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r = r.WithContext(ContextWithUser(r.Context(), User{ID: "foo", Roles: tt.roles}))
+
+			server.RequireRole(authenticated, RoleStaff, RoleBusinessOwner)(w, r)
+			assert.Equal(t, tt.status, w.Result().StatusCode)
+		})
+	}
+}
+
+func TestSetUserRoles(t *testing.T) {
+	roleManager := &fakeRoleManager{
+		roles: map[string][]Role{"bar": nil},
+	}
+	server := NewServer(nil, nil, nil, nil, nil,
+		WithTokenVerifier(authtest.Verifier(projectID)),
+		WithRoleManager(roleManager),
+	)
+	admin := mintToken(t, authtoken.MintInput{
+		UID:            "foo",
+		SignInProvider: "phone",
+		Claims:         map[string]interface{}{"roles": []string{"admin"}},
+	})
+	notAdmin := mintToken(t, authtoken.MintInput{
+		UID:            "foo",
+		SignInProvider: "phone",
+		Claims:         map[string]interface{}{"roles": []string{"business_owner"}},
+	})
+
+	tests := []struct {
+		name   string
+		token  string
+		uid    string
+		body   string
+		status int
+	}{
+		{"not an admin", notAdmin, "bar", `{"roles":["admin"]}`, http.StatusForbidden},
+		{"invalid role", admin, "bar", `{"roles":["superuser"]}`, http.StatusBadRequest},
+		{"unknown user", admin, "baz", `{"roles":["staff"]}`, http.StatusNotFound},
+		{"admin", admin, "bar", `{"roles":["staff","client"]}`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/admin/users/"+tt.uid+"/roles", bytes.NewReader([]byte(tt.body)))
+			r.Header.Set("Authorization", "Bearer "+tt.token)
+
+			server.AdminRouter(w, r)
+			assert.Equal(t, tt.status, w.Result().StatusCode)
+		})
+	}
+
+	assert.Equal(t, []Role{RoleStaff, RoleClient}, roleManager.roles["bar"])
+}
@@ -6,36 +6,73 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"path"
 	"strings"
 	"time"
 
 	firebase "firebase.google.com/go/v4"
+	"firebase.google.com/go/v4/auth"
 	"github.com/devduck123/servizio-be/internal/appointmentdao"
+	"github.com/devduck123/servizio-be/internal/authtoken"
 	"github.com/devduck123/servizio-be/internal/businessdao"
 	"github.com/devduck123/servizio-be/internal/clientdao"
 	"github.com/devduck123/servizio-be/internal/images"
+	"github.com/devduck123/servizio-be/internal/logging"
+	"github.com/devduck123/servizio-be/internal/metrics"
+	"github.com/devduck123/servizio-be/internal/tracing"
 )
 
 type Server struct {
-	businessDao    *businessdao.Dao
-	clientDao      *clientdao.Dao
-	appointmentDao *appointmentdao.Dao
-	imageManager   *images.ImageManager
+	businessDao    businessdao.Store
+	clientDao      clientdao.Store
+	appointmentDao appointmentdao.Store
+	imageManager   images.Store
 	app            *firebase.App
+	tokenVerifier  authtoken.Verifier
+	authPolicy     AuthPolicy
+	roleManager    RoleManager
+	metrics        *metrics.Metrics
+	maxImageBytes  int64
 }
 
-func NewServer(businessDao *businessdao.Dao, clientDao *clientdao.Dao, appointmentDao *appointmentdao.Dao, imageManager *images.ImageManager, app *firebase.App) *Server {
-	return &Server{
+type Option func(*Server)
+
+// WithTokenVerifier replaces Firebase Auth as the verifier of ID tokens.
+func WithTokenVerifier(verifier authtoken.Verifier) Option {
+	return func(s *Server) {
+		s.tokenVerifier = verifier
+	}
+}
+
+// NewServer takes the Firestore and Cloud Storage backed stores in
+// production, tests and local runs can pass the in-memory ones instead.
+func NewServer(businessDao businessdao.Store, clientDao clientdao.Store, appointmentDao appointmentdao.Store, imageManager images.Store, app *firebase.App, opts ...Option) *Server {
+	s := &Server{
 		businessDao:    businessDao,
 		clientDao:      clientDao,
 		appointmentDao: appointmentDao,
 		imageManager:   imageManager,
 		app:            app,
+		authPolicy:     DefaultAuthPolicy(),
+		maxImageBytes:  defaultMaxImageBytes,
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	if s.tokenVerifier == nil {
+		s.tokenVerifier = authtoken.NewFirebase(app)
+	}
+	if s.roleManager == nil {
+		s.roleManager = firebaseRoleManager{app: app}
+	}
+
+	return s
 }
 
 type User struct {
 	ID string
+	// Roles come from the roles custom claim of the token
+	Roles []Role
 }
 
 var UserKey struct{}
@@ -44,45 +81,55 @@ func (s *Server) Authenticate(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 
-		idToken := r.Header.Get("Authorization")
-
-		client, err := s.app.Auth(ctx)
+		token, err := s.verifyRequest(ctx, r)
 		if err != nil {
-			fmt.Printf("error getting Auth client: %v\n", err)
-			writeErrorJSON(w, http.StatusInternalServerError, errors.New("something went wrong"))
-			return
-		}
-
-		token, err := client.VerifyIDToken(ctx, idToken)
-		if err != nil {
-			fmt.Printf("error verifying ID token: %v\n", err)
-			writeErrorJSON(w, http.StatusUnauthorized, errors.New("invalid credentials"))
-			return
-		}
-
-		expiresAt := time.Unix(token.Expires, 0)
-		if time.Now().After(expiresAt) {
-			writeErrorJSON(w, http.StatusUnauthorized, errors.New("token expired"))
-			return
-		}
-
-		// TODO: verify, or not?
-		emailVerified := token.Claims["email_verified"].(bool)
-		if !emailVerified {
-			writeErrorJSON(w, http.StatusUnauthorized, errors.New("email not verified"))
+			writeErrorJSON(w, http.StatusUnauthorized, err)
 			return
 		}
 
 		user := User{
-			ID: token.Subject,
+			ID:    token.Subject,
+			Roles: rolesFromClaims(token.Claims),
 		}
 		// update context with user object and UID
+		ctx = setRequestUser(ctx, user.ID)
 		r = r.WithContext(context.WithValue(ctx, UserKey, user))
 
 		next(w, r)
 	}
 }
 
+// verifyRequest checks the ID token of r against the auth policy, in its
+// own span since verification may call out to Firebase.
+func (s *Server) verifyRequest(ctx context.Context, r *http.Request) (token *auth.Token, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "Authenticate")
+	defer func() {
+		tracing.End(span, err)
+	}()
+
+	idToken, err := s.authPolicy.idToken(r.Header.Get("Authorization"))
+	if err != nil {
+		return nil, err
+	}
+
+	token, err = s.tokenVerifier.VerifyIDToken(ctx, idToken)
+	if err != nil {
+		logging.FromContext(ctx).Warn("error verifying ID token", "error", err)
+		return nil, errors.New("invalid credentials")
+	}
+
+	expiresAt := time.Unix(token.Expires, 0)
+	if time.Now().After(expiresAt) {
+		return nil, errors.New("token expired")
+	}
+
+	if err := s.authPolicy.check(token); err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
 func UserFromContext(ctx context.Context) (User, error) {
 	rawUser := ctx.Value(UserKey)
 	if rawUser == nil {
@@ -101,16 +148,6 @@ func ContextWithUser(ctx context.Context, user User) context.Context {
 	return context.WithValue(ctx, UserKey, user)
 }
 
-func (s *Server) Logger(next http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		t := time.Now()
-		next(w, r)
-		duration := time.Since(t)
-
-		fmt.Printf("%v: %v %v took %v\n", t.Format(time.RFC3339), r.Method, r.URL.Path, duration)
-	}
-}
-
 func (s *Server) CORS(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
@@ -124,9 +161,18 @@ func (s *Server) CORS(next http.HandlerFunc) http.HandlerFunc {
 func (s *Server) BusinessRouter(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
+		trimmedURL := strings.TrimSuffix(r.URL.Path, "/")
+		if strings.HasSuffix(trimmedURL, "/availability") {
+			s.GetBusinessAvailability(w, r)
+			return
+		}
 		businessID := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/businesses"), "/")
-		fmt.Println("id:", businessID)
 		if businessID == "" {
+			// only listing your own businesses requires a user
+			if r.URL.Query().Get("owner") != "" {
+				s.Authenticate(s.GetAllBusinesses)(w, r)
+				return
+			}
 			s.GetAllBusinesses(w, r)
 			return
 		}
@@ -140,7 +186,21 @@ func (s *Server) BusinessRouter(w http.ResponseWriter, r *http.Request) {
 		}
 		s.Authenticate(s.CreateBusiness)(w, r)
 		return
+	case http.MethodPatch:
+		s.Authenticate(s.UpdateBusiness)(w, r)
+		return
+	case http.MethodPut:
+		trimmedURL := strings.TrimSuffix(r.URL.Path, "/")
+		if strings.HasSuffix(trimmedURL, "/images/order") {
+			s.Authenticate(s.OrderImagesBusiness)(w, r)
+			return
+		}
+		writeErrorJSON(w, http.StatusNotFound, fmt.Errorf("%v not found", r.URL.Path))
 	case http.MethodDelete:
+		if strings.Contains(r.URL.Path, "/images/") {
+			s.Authenticate(s.DeleteImageBusiness)(w, r)
+			return
+		}
 		s.Authenticate(s.DeleteBusiness)(w, r)
 		return
 	case http.MethodOptions:
@@ -158,6 +218,10 @@ func (s *Server) ClientRouter(w http.ResponseWriter, r *http.Request) {
 			s.GetAllClients(w, r)
 			return
 		}
+		if clientID == "/me" {
+			s.Authenticate(s.GetMyClient)(w, r)
+			return
+		}
 		s.GetClient(w, r)
 		return
 	case http.MethodPost:
@@ -168,7 +232,14 @@ func (s *Server) ClientRouter(w http.ResponseWriter, r *http.Request) {
 		}
 		s.Authenticate(s.CreateClient)(w, r)
 		return
+	case http.MethodPatch:
+		s.Authenticate(s.UpdateClient)(w, r)
+		return
 	case http.MethodDelete:
+		if strings.Contains(r.URL.Path, "/images/") {
+			s.Authenticate(s.DeleteImageClient)(w, r)
+			return
+		}
 		s.Authenticate(s.DeleteClient)(w, r)
 		return
 	case http.MethodOptions:
@@ -182,7 +253,6 @@ func (s *Server) AppointmentRouter(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
 		appointmentID := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/appointments"), "/")
-		fmt.Println("id:", appointmentID)
 		if appointmentID == "" {
 			s.GetAllAppointments(w, r)
 			return
@@ -190,8 +260,16 @@ func (s *Server) AppointmentRouter(w http.ResponseWriter, r *http.Request) {
 		s.GetAppointment(w, r)
 		return
 	case http.MethodPost:
+		trimmedURL := strings.TrimSuffix(r.URL.Path, "/")
+		if _, ok := appointmentStatusActions[path.Base(trimmedURL)]; ok {
+			s.Authenticate(s.ChangeAppointmentStatus)(w, r)
+			return
+		}
 		s.Authenticate(s.CreateAppointment)(w, r)
 		return
+	case http.MethodPatch:
+		s.Authenticate(s.RescheduleAppointment)(w, r)
+		return
 	case http.MethodDelete:
 		s.Authenticate(s.DeleteAppointment)(w, r)
 		return
@@ -202,14 +280,45 @@ func (s *Server) AppointmentRouter(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (s *Server) AdminRouter(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		trimmedURL := strings.TrimSuffix(r.URL.Path, "/")
+		if strings.HasPrefix(trimmedURL, "/admin/users/") && strings.HasSuffix(trimmedURL, "/roles") {
+			s.Authenticate(s.RequireRole(s.SetUserRoles, RoleAdmin))(w, r)
+			return
+		}
+		writeErrorJSON(w, http.StatusNotFound, fmt.Errorf("%v not found", r.URL.Path))
+	case http.MethodOptions:
+		return
+	default:
+		writeErrorJSON(w, http.StatusNotImplemented, fmt.Errorf("%v not implemented yet", r.Method))
+	}
+}
+
+// writeErrorJSON answers with err as the error message, except for server
+// errors whose cause may expose internals: the client gets the status text
+// and the cause is logged by Logger instead.
 func writeErrorJSON(w http.ResponseWriter, status int, err error) {
+	rw, recorded := w.(*ResponseWriter)
+	if recorded {
+		rw.err = err
+	}
+	message := err.Error()
+	if status >= http.StatusInternalServerError {
+		message = http.StatusText(status)
+		if !recorded {
+			logging.Default().Error("server error outside of Logger", "status", status, "error", err)
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
 
 	var response struct {
 		Error string `json:"error"`
 	}
-	response.Error = err.Error()
+	response.Error = message
 	raw, _ := json.Marshal(response)
 	w.Write(raw)
 }
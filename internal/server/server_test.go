@@ -4,18 +4,23 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path"
+	"path/filepath"
+	"strings"
 	"testing"
 
-	"cloud.google.com/go/firestore"
 	firebase "firebase.google.com/go/v4"
 	"github.com/devduck123/servizio-be/internal/authtest"
+	"github.com/devduck123/servizio-be/internal/authtoken"
 	"github.com/devduck123/servizio-be/internal/businessdao"
+	"github.com/devduck123/servizio-be/internal/clientdao"
 	"github.com/devduck123/servizio-be/internal/images"
 	"github.com/tj/assert"
 )
@@ -36,22 +41,19 @@ func TestMain(m *testing.M) {
 	m.Run()
 }
 
-func deleteCollection(ctx context.Context, t *testing.T, fsClient *firestore.Client, collection string) {
-	documentRefs, err := fsClient.Collection(collection).DocumentRefs(ctx).GetAll()
-	assert.NoError(t, err)
+func createTestImageManager(ctx context.Context, t *testing.T) images.Store {
+	t.Helper()
 
-	for _, document := range documentRefs {
-		_, err := document.Delete(ctx)
-		assert.NoError(t, err)
-	}
+	return images.NewMemoryStore()
 }
 
-func createTestImageManager(ctx context.Context, t *testing.T) *images.ImageManager {
-	im := &images.ImageManager{
-		BucketName: "servizio-be.appspot.com",
-	}
+// readSampleImage reads one of the real images kept for the images tests.
+func readSampleImage(t *testing.T, name string) []byte {
+	t.Helper()
 
-	return im
+	raw, err := ioutil.ReadFile(filepath.Join("..", "images", "testdata", name))
+	assert.NoError(t, err)
+	return raw
 }
 
 func TestUploadImage(t *testing.T) {
@@ -59,15 +61,17 @@ func TestUploadImage(t *testing.T) {
 	dao := createTestBusinessDao(ctx, t)
 
 	business, err := dao.Create(ctx, businessdao.CreateInput{
-		Name: "foo",
+		Name:   "foo",
+		UserID: "owner",
 	})
 	assert.NoError(t, err)
 
 	im := createTestImageManager(ctx, t)
 	server := NewServer(dao, nil, nil, im, nil)
-	body := bytes.NewReader([]byte("hello"))
+	body := bytes.NewReader(readSampleImage(t, "sample.jpeg"))
 	w := httptest.NewRecorder()
 	r := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/businesses/%v/images/", business.ID), body)
+	r = r.WithContext(ContextWithUser(ctx, User{ID: "owner"}))
 
 	server.UploadImageBusiness(w, r)
 
@@ -76,6 +80,304 @@ func TestUploadImage(t *testing.T) {
 	gotBody, err := ioutil.ReadAll(w.Result().Body)
 	assert.NoError(t, err)
 	assert.Equal(t, `"success"`, string(gotBody))
+
+	business, err = dao.GetBusiness(ctx, business.ID)
+	assert.NoError(t, err)
+	assert.Len(t, business.Images, 1)
+	assert.Len(t, business.Images[0].Variants, 3)
+	for _, size := range []string{"128", "512", "1024"} {
+		assert.Contains(t, business.Images[0].Variants, size)
+	}
+}
+
+func TestUploadImage_Forbidden(t *testing.T) {
+	ctx := context.Background()
+	businessDao := createTestBusinessDao(ctx, t)
+	clientDao := createTestClientDao(ctx, t)
+
+	business, err := businessDao.Create(ctx, businessdao.CreateInput{
+		Name:   "foo",
+		UserID: "owner",
+	})
+	assert.NoError(t, err)
+	client, err := clientDao.Create(ctx, clientdao.CreateInput{
+		FirstName: "foo",
+		LastName:  "bar",
+		UserID:    "client",
+	})
+	assert.NoError(t, err)
+
+	im := createTestImageManager(ctx, t)
+	server := NewServer(businessDao, clientDao, nil, im, nil)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/businesses/%v/images/", business.ID), bytes.NewReader(readSampleImage(t, "sample.png")))
+	r = r.WithContext(ContextWithUser(ctx, User{ID: "stranger"}))
+	server.UploadImageBusiness(w, r)
+	assert.Equal(t, http.StatusForbidden, w.Result().StatusCode)
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodPost, fmt.Sprintf("/clients/%v/images/", client.ID), bytes.NewReader(readSampleImage(t, "sample.png")))
+	r = r.WithContext(ContextWithUser(ctx, User{ID: "stranger"}))
+	server.UploadImageClient(w, r)
+	assert.Equal(t, http.StatusForbidden, w.Result().StatusCode)
+}
+
+func TestUploadImage_Rejected(t *testing.T) {
+	ctx := context.Background()
+	businessDao := createTestBusinessDao(ctx, t)
+	clientDao := createTestClientDao(ctx, t)
+
+	business, err := businessDao.Create(ctx, businessdao.CreateInput{
+		Name:   "foo",
+		UserID: "owner",
+	})
+	assert.NoError(t, err)
+	client, err := clientDao.Create(ctx, clientdao.CreateInput{
+		FirstName: "foo",
+		LastName:  "bar",
+		UserID:    "owner",
+	})
+	assert.NoError(t, err)
+
+	im := images.NewMemoryStore()
+	jpeg := readSampleImage(t, "sample.jpeg")
+	server := NewServer(businessDao, clientDao, nil, im, nil, WithMaxImageBytes(int64(len(jpeg))))
+
+	tests := []struct {
+		name   string
+		raw    []byte
+		status int
+	}{
+		{"jpeg", jpeg, http.StatusOK},
+		{"png", readSampleImage(t, "sample.png"), http.StatusOK},
+		{"webp", readSampleImage(t, "sample.webp"), http.StatusOK},
+		{"too large", append(jpeg, 0), http.StatusRequestEntityTooLarge},
+		{"gif", readSampleImage(t, "sample.gif"), http.StatusUnsupportedMediaType},
+		{"text", []byte("hello"), http.StatusUnsupportedMediaType},
+		{"truncated jpeg", readSampleImage(t, "truncated.jpeg"), http.StatusBadRequest},
+		{"empty", nil, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/businesses/%v/images/", business.ID), bytes.NewReader(tt.raw))
+			r = r.WithContext(ContextWithUser(ctx, User{ID: "owner"}))
+			server.UploadImageBusiness(w, r)
+			assert.Equal(t, tt.status, w.Result().StatusCode)
+
+			w = httptest.NewRecorder()
+			r = httptest.NewRequest(http.MethodPost, fmt.Sprintf("/clients/%v/images/", client.ID), bytes.NewReader(tt.raw))
+			r = r.WithContext(ContextWithUser(ctx, User{ID: "owner"}))
+			server.UploadImageClient(w, r)
+			assert.Equal(t, tt.status, w.Result().StatusCode)
+		})
+	}
+
+	// chunked bodies have no length to check up front
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/businesses/%v/images/", business.ID), ioutil.NopCloser(bytes.NewReader(append(jpeg, 0))))
+	r.ContentLength = -1
+	r = r.WithContext(ContextWithUser(ctx, User{ID: "owner"}))
+	server.UploadImageBusiness(w, r)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Result().StatusCode)
+
+	updated, err := businessDao.GetBusiness(ctx, business.ID)
+	assert.NoError(t, err)
+	assert.Len(t, updated.Images, 3)
+}
+
+func TestDeleteAndOrderImages(t *testing.T) {
+	ctx := context.Background()
+	businessDao := createTestBusinessDao(ctx, t)
+	clientDao := createTestClientDao(ctx, t)
+	im := images.NewMemoryStore()
+	server := NewServer(businessDao, clientDao, nil, im, nil, WithTokenVerifier(authtest.Verifier(projectID)))
+
+	business, err := businessDao.Create(ctx, businessdao.CreateInput{
+		Name:   "foo",
+		UserID: "owner",
+	})
+	assert.NoError(t, err)
+	client, err := clientDao.Create(ctx, clientdao.CreateInput{
+		FirstName: "foo",
+		UserID:    "owner",
+	})
+	assert.NoError(t, err)
+
+	do := func(method, target, uid string, body []byte) *http.Response {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(method, target, bytes.NewReader(body))
+		r.Header.Set("Authorization", "Bearer "+mintToken(t, authtoken.MintInput{UID: uid, SignInProvider: "phone"}))
+		if strings.HasPrefix(target, "/clients") {
+			server.ClientRouter(w, r)
+		} else {
+			server.BusinessRouter(w, r)
+		}
+		return w.Result()
+	}
+
+	businessImages := fmt.Sprintf("/businesses/%v/images", business.ID)
+	for i := 0; i < 2; i++ {
+		resp := do(http.MethodPost, businessImages, "owner", readSampleImage(t, "sample.jpeg"))
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+	stored, err := businessDao.GetBusiness(ctx, business.ID)
+	assert.NoError(t, err)
+	assert.Len(t, stored.Images, 2)
+	first, second := path.Base(stored.Images[0].Key), path.Base(stored.Images[1].Key)
+
+	order, _ := json.Marshal(ImageOrderInput{Order: []string{second, first}, Cover: second})
+	resp := do(http.MethodPut, businessImages+"/order", "owner", order)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var ordered businessdao.Business
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&ordered))
+	assert.Equal(t, stored.Images[1].Key, ordered.Images[0].Key)
+	assert.Equal(t, stored.Images[1].Key, ordered.CoverImage)
+
+	for _, input := range []ImageOrderInput{
+		{Order: []string{second}},
+		{Order: []string{second, first}, Cover: "notexists"},
+	} {
+		body, _ := json.Marshal(input)
+		resp := do(http.MethodPut, businessImages+"/order", "owner", body)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	}
+	resp = do(http.MethodPut, businessImages+"/order", "stranger", order)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp = do(http.MethodDelete, businessImages+"/"+second, "stranger", nil)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	resp = do(http.MethodDelete, businessImages+"/"+second, "owner", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp = do(http.MethodDelete, businessImages+"/"+second, "owner", nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// the objects go with the entry, and the cover with them
+	key := imageKey(business.ID, second)
+	_, err = im.GetImage(ctx, key)
+	assert.Equal(t, images.ErrImageNotFound, err)
+	_, err = im.GetImage(ctx, images.VariantKey(key, 128))
+	assert.Equal(t, images.ErrImageNotFound, err)
+	_, err = im.GetImage(ctx, imageKey(business.ID, first))
+	assert.NoError(t, err)
+	stored, err = businessDao.GetBusiness(ctx, business.ID)
+	assert.NoError(t, err)
+	assert.Len(t, stored.Images, 1)
+	assert.Empty(t, stored.CoverImage)
+
+	clientImages := fmt.Sprintf("/clients/%v/images", client.ID)
+	resp = do(http.MethodPost, clientImages, "owner", readSampleImage(t, "sample.png"))
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	storedClient, err := clientDao.GetClient(ctx, client.ID)
+	assert.NoError(t, err)
+	name := path.Base(storedClient.Images[0].Key)
+
+	resp = do(http.MethodDelete, clientImages+"/"+name, "stranger", nil)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	resp = do(http.MethodDelete, clientImages+"/"+name, "owner", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	_, err = im.GetImage(ctx, imageKey(client.ID, name))
+	assert.Equal(t, images.ErrImageNotFound, err)
+	storedClient, err = clientDao.GetClient(ctx, client.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, storedClient.Images)
+}
+
+// failingRemoveStore is a business store that cannot remove images.
+type failingRemoveStore struct {
+	*businessdao.MemoryStore
+}
+
+func (s failingRemoveStore) RemoveImage(ctx context.Context, id string, key string) error {
+	return errors.New("transaction aborted")
+}
+
+// failingRemoveClientStore is a client store that cannot remove images.
+type failingRemoveClientStore struct {
+	*clientdao.MemoryStore
+}
+
+func (s failingRemoveClientStore) RemoveImage(ctx context.Context, id string, key string) error {
+	return errors.New("transaction aborted")
+}
+
+// failingDeleteStore is an image store that cannot delete images.
+type failingDeleteStore struct {
+	*images.MemoryStore
+}
+
+func (s failingDeleteStore) DeleteImage(ctx context.Context, key string) error {
+	return errors.New("storage unavailable")
+}
+
+func TestDeleteImageBusiness_Failures(t *testing.T) {
+	ctx := context.Background()
+	memory := businessdao.NewMemoryStore()
+	im := images.NewMemoryStore()
+
+	business, err := memory.Create(ctx, businessdao.CreateInput{Name: "foo", UserID: "owner"})
+	assert.NoError(t, err)
+	upload, err := images.Prepare(readSampleImage(t, "sample.jpeg"))
+	assert.NoError(t, err)
+	image, err := im.UploadImage(ctx, business.ID, upload)
+	assert.NoError(t, err)
+	assert.NoError(t, memory.AppendImage(ctx, business.ID, image.Ref()))
+	target := fmt.Sprintf("/businesses/%v/images/%v", business.ID, path.Base(image.Key))
+
+	del := func(server *Server) *http.Response {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodDelete, target, nil)
+		r = r.WithContext(ContextWithUser(ctx, User{ID: "owner"}))
+		server.DeleteImageBusiness(w, r)
+		return w.Result()
+	}
+
+	// the image is kept whole when its entry cannot be removed
+	resp := del(NewServer(failingRemoveStore{memory}, nil, nil, im, nil))
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	_, err = im.GetImage(ctx, image.Key)
+	assert.NoError(t, err)
+	stored, err := memory.GetBusiness(ctx, business.ID)
+	assert.NoError(t, err)
+	assert.Len(t, stored.Images, 1)
+
+	// an object left in storage is only orphaned
+	resp = del(NewServer(memory, nil, nil, failingDeleteStore{im}, nil))
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	stored, err = memory.GetBusiness(ctx, business.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, stored.Images)
+	_, err = im.GetImage(ctx, image.Key)
+	assert.NoError(t, err)
+}
+
+func TestDeleteImageClient_RemoveFails(t *testing.T) {
+	ctx := context.Background()
+	memory := clientdao.NewMemoryStore()
+	im := images.NewMemoryStore()
+	server := NewServer(nil, failingRemoveClientStore{memory}, nil, im, nil)
+
+	client, err := memory.Create(ctx, clientdao.CreateInput{FirstName: "foo", UserID: "owner"})
+	assert.NoError(t, err)
+	upload, err := images.Prepare(readSampleImage(t, "sample.jpeg"))
+	assert.NoError(t, err)
+	image, err := im.UploadImage(ctx, client.ID, upload)
+	assert.NoError(t, err)
+	assert.NoError(t, memory.AppendImage(ctx, client.ID, image.Ref()))
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/clients/%v/images/%v", client.ID, path.Base(image.Key)), nil)
+	r = r.WithContext(ContextWithUser(ctx, User{ID: "owner"}))
+	server.DeleteImageClient(w, r)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
+	_, err = im.GetImage(ctx, image.Key)
+	assert.NoError(t, err)
+	for _, size := range images.VariantSizes {
+		_, err = im.GetImage(ctx, images.VariantKey(image.Key, size))
+		assert.NoError(t, err)
+	}
 }
 
 func TestServer(t *testing.T) {
@@ -85,7 +387,7 @@ func TestServer(t *testing.T) {
 		ProjectID: projectID,
 	})
 	assert.NoError(t, err)
-	server := NewServer(dao, nil, nil, nil, app) // This is the constructor that creates a "server"
+	server := NewServer(dao, nil, nil, nil, app, WithTokenVerifier(authtest.Verifier(projectID))) // This is the constructor that creates a "server"
 
 	httpServer := httptest.NewServer(http.HandlerFunc(server.BusinessRouter)) // This spins up a HTTP test server.
 	defer httpServer.Close()
@@ -172,10 +474,12 @@ func TestServer_GetAllBusinesses(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, http.StatusOK, res.StatusCode)
 
-	var response []businessdao.Business
+	var response struct {
+		Items []businessdao.Business `json:"items"`
+	}
 	err = json.NewDecoder(res.Body).Decode(&response)
 	assert.NoError(t, err)
-	assert.Equal(t, 1, len(response))
+	assert.Equal(t, 1, len(response.Items))
 }
 
 func TestUserFromContext_NotFound(t *testing.T) {
@@ -0,0 +1,37 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/devduck123/servizio-be/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const serverName = "servizio"
+
+// Trace starts a span for every request to route, continuing the trace of
+// the W3C traceparent header when the caller sent one.
+func (s *Server) Trace(route string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracing.Tracer().Start(ctx, r.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(semconv.HTTPServerAttributesFromHTTPRequest(serverName, route, r)...),
+		)
+		defer span.End()
+
+		rw := NewResponseWriter(w)
+		next(rw, r.WithContext(ctx))
+
+		span.SetAttributes(semconv.HTTPAttributesFromHTTPStatusCode(rw.Status())...)
+		code, description := semconv.SpanStatusFromHTTPStatusCodeAndSpanKind(rw.Status(), trace.SpanKindServer)
+		if rw.Err() != nil && rw.Status() >= http.StatusInternalServerError {
+			span.RecordError(rw.Err())
+			description = rw.Err().Error()
+		}
+		span.SetStatus(code, description)
+	}
+}
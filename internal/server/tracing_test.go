@@ -0,0 +1,108 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/devduck123/servizio-be/internal/authtest"
+	"github.com/devduck123/servizio-be/internal/authtoken"
+	"github.com/devduck123/servizio-be/internal/logging"
+	"github.com/tj/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	previousProvider := otel.GetTracerProvider()
+	previousPropagator := otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+	return recorder
+}
+
+func TestTrace_ContinuesIncomingTrace(t *testing.T) {
+	recorder := recordSpans(t)
+	server := NewServer(nil, nil, nil, nil, nil, WithTokenVerifier(authtest.Verifier(projectID)))
+	handler := server.Trace("/appointments/", server.Authenticate(authenticated))
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/appointments/", nil)
+	r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.Header.Set("Authorization", "Bearer "+mintToken(t, authtoken.MintInput{UID: "foo", SignInProvider: "phone"}))
+	handler(w, r)
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 2)
+
+	auth, request := spans[0], spans[1]
+	assert.Equal(t, "Authenticate", auth.Name())
+	assert.Equal(t, request.SpanContext().SpanID(), auth.Parent().SpanID())
+
+	assert.Equal(t, "POST /appointments/", request.Name())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", request.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", request.Parent().SpanID().String())
+	assert.True(t, request.Parent().IsRemote())
+	assert.Equal(t, codes.Unset, request.Status().Code)
+}
+
+func TestTrace_Errors(t *testing.T) {
+	recorder := recordSpans(t)
+	server := NewServer(nil, nil, nil, nil, nil, WithTokenVerifier(authtest.Verifier(projectID)))
+
+	// a rejected token fails the auth span but not the request span
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/appointments/", nil)
+	r.Header.Set("Authorization", "Bearer nope")
+	server.Trace("/appointments/", server.Authenticate(authenticated))(w, r)
+	assert.Equal(t, http.StatusUnauthorized, w.Result().StatusCode)
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 2)
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Equal(t, codes.Unset, spans[1].Status().Code)
+	// no traceparent starts a new trace
+	assert.False(t, spans[1].Parent().IsValid())
+
+	// a server error fails the request span with its cause
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, "/businesses/", nil)
+	server.Trace("/businesses/", func(w http.ResponseWriter, r *http.Request) {
+		writeErrorJSON(w, http.StatusInternalServerError, errors.New("firestore unavailable"))
+	})(w, r)
+
+	spans = recorder.Ended()
+	assert.Len(t, spans, 3)
+	assert.Equal(t, codes.Error, spans[2].Status().Code)
+	assert.Equal(t, "firestore unavailable", spans[2].Status().Description)
+}
+
+func TestTrace_LogsCarryTraceID(t *testing.T) {
+	recordSpans(t)
+	server := NewServer(nil, nil, nil, nil, nil)
+	handler := server.Trace("/businesses/", server.Logger(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, "ok")
+	}))
+
+	var buf bytes.Buffer
+	r := httptest.NewRequest(http.MethodGet, "/businesses/", nil)
+	r = r.WithContext(logging.NewContext(r.Context(), logging.New(&buf, logging.LevelInfo)))
+	r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	handler(httptest.NewRecorder(), r)
+
+	entries := logEntries(t, &buf)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", entries[0]["traceId"])
+	assert.NotEmpty(t, entries[0]["spanId"])
+}
@@ -0,0 +1,112 @@
+// Package tracing sets up OpenTelemetry and holds the tracer used across
+// the server, the stores and the image manager.
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/devduck123/servizio-be/internal/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	instrumentationName = "github.com/devduck123/servizio-be"
+	serviceName         = "servizio"
+
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+// Tracer returns the tracer of the global provider installed by Setup, a
+// no-op one until then.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Setup installs the global tracer provider exporting to cfg.Exporter and
+// the W3C trace context propagator. Spans are not exported with
+// ExporterNone but incoming traceparent headers are still propagated. The
+// returned func flushes the spans not exported yet.
+func Setup(ctx context.Context, cfg config.Tracing) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	var exporter sdktrace.SpanExporter
+	switch cfg.Exporter {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		var err error
+		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+		if err != nil {
+			return nil, err
+		}
+	case ExporterOTLP:
+		// without an endpoint the exporter reads OTEL_EXPORTER_OTLP_ENDPOINT
+		// and falls back to localhost:4318
+		var opts []otlptracehttp.Option
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		var err error
+		exporter, err = otlptracehttp.New(ctx, opts...)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", cfg.Exporter)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceNameKey.String(serviceName),
+		)),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// ObserveStore starts a span for every call of a store, it is an
+// observe.Func for the Instrument funcs of the dao packages.
+func ObserveStore(ctx context.Context, store, method string) (context.Context, func(err error)) {
+	ctx, span := Tracer().Start(ctx, store+"."+method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemKey.String("firestore"),
+			attribute.String("servizio.store", store),
+			semconv.DBOperationKey.String(method),
+		),
+	)
+
+	return ctx, func(err error) {
+		End(span, err)
+	}
+}
+
+// End records err, if any, on span and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,74 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/devduck123/servizio-be/internal/config"
+	"github.com/tj/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recordSpans installs a tracer provider keeping the ended spans in memory
+// for the rest of the test.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+	})
+	return recorder
+}
+
+func TestObserveStore(t *testing.T) {
+	recorder := recordSpans(t)
+	ctx, parent := Tracer().Start(context.Background(), "POST /appointments/")
+
+	storeCtx, done := ObserveStore(ctx, "appointmentdao", "Create")
+	done(errors.New("deadline exceeded"))
+	_, done = ObserveStore(ctx, "appointmentdao", "GetAppointment")
+	done(nil)
+	parent.End()
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 3)
+
+	create := spans[0]
+	assert.Equal(t, "appointmentdao.Create", create.Name())
+	assert.Equal(t, parent.SpanContext().SpanID(), create.Parent().SpanID())
+	assert.Equal(t, create.SpanContext().SpanID(), otelSpanID(storeCtx))
+	assert.Equal(t, codes.Error, create.Status().Code)
+	assert.Equal(t, "deadline exceeded", create.Status().Description)
+	assert.Len(t, create.Events(), 1)
+
+	get := spans[1]
+	assert.Equal(t, "appointmentdao.GetAppointment", get.Name())
+	assert.Equal(t, codes.Unset, get.Status().Code)
+}
+
+func TestSetup(t *testing.T) {
+	ctx := context.Background()
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+	})
+
+	for _, exporter := range []string{"", ExporterNone, ExporterStdout, ExporterOTLP} {
+		shutdown, err := Setup(ctx, config.Tracing{Exporter: exporter, Endpoint: "localhost:4318", Insecure: true})
+		assert.NoError(t, err, exporter)
+		assert.NoError(t, shutdown(ctx), exporter)
+	}
+
+	_, err := Setup(ctx, config.Tracing{Exporter: "jaeger"})
+	assert.Error(t, err)
+}
+
+func otelSpanID(ctx context.Context) trace.SpanID {
+	return trace.SpanContextFromContext(ctx).SpanID()
+}
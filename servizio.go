@@ -3,49 +3,28 @@ package servizio
 import (
 	"context"
 	"net/http"
+	"os"
 
-	"cloud.google.com/go/firestore"
-	firebase "firebase.google.com/go/v4"
 	"github.com/GoogleCloudPlatform/functions-framework-go/functions"
-	"github.com/devduck123/servizio-be/internal/appointmentdao"
-	"github.com/devduck123/servizio-be/internal/businessdao"
-	"github.com/devduck123/servizio-be/internal/clientdao"
-	"github.com/devduck123/servizio-be/internal/images"
-	"github.com/devduck123/servizio-be/internal/server"
+	"github.com/devduck123/servizio-be/internal/bootstrap"
+	"github.com/devduck123/servizio-be/internal/config"
 )
 
 func setupServer() (http.Handler, error) {
 	ctx := context.Background()
 
-	projectID := "servizio-be"
-
-	fsClient, err := firestore.NewClient(ctx, projectID)
+	// the function has no command line, it is configured through env vars
+	cfg, err := config.Load(nil, os.LookupEnv)
 	if err != nil {
 		return nil, err
 	}
 
-	businessDao := businessdao.NewDao(fsClient, "businesses")
-	clientDao := clientdao.NewDao(fsClient, "clients")
-	appointmentDao := appointmentdao.NewDao(fsClient, "appointments")
-
-	im := &images.ImageManager{
-		BucketName: "servizio-be.appspot.com",
-	}
-	app, err := firebase.NewApp(ctx, &firebase.Config{
-		ProjectID: projectID,
-	})
+	app, err := bootstrap.Build(ctx, cfg)
 	if err != nil {
 		return nil, err
 	}
 
-	s := server.NewServer(businessDao, clientDao, appointmentDao, im, app)
-
-	sm := http.NewServeMux()
-	sm.Handle("/businesses/", s.Logger(s.CORS(s.BusinessRouter)))
-	sm.Handle("/clients/", s.Logger(s.CORS(s.ClientRouter)))
-	sm.Handle("/appointments/", s.Logger(s.CORS(s.AppointmentRouter)))
-
-	return sm, nil
+	return app.Handler, nil
 }
 
 func init() {